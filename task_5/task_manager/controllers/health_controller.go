@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct{}
+
+func NewHealthController() *HealthController {
+	return &HealthController{}
+}
+
+// Healthz reports that the process is up and serving.
+func (hc *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the server can handle requests. Tasks are kept in
+// memory, so there is nothing external to wait for.
+func (hc *HealthController) Readyz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
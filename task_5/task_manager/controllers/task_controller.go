@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	database "task_manager/data"
+	"task_manager/problem"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,7 +18,7 @@ func NewTaskController() *TaskController {
 func (tc *TaskController) CreateTask(c *gin.Context) {
 	var task database.TaskModel
 	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
@@ -28,13 +29,13 @@ func (tc *TaskController) CreateTask(c *gin.Context) {
 func (tc *TaskController) GetTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
 	task, found := database.GetTaskByID(id)
 	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeTaskNotFound))
 		return
 	}
 
@@ -49,19 +50,19 @@ func (tc *TaskController) GetAllTasks(c *gin.Context) {
 func (tc *TaskController) UpdateTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
 	var updatedTask database.TaskModel
 	if err := c.ShouldBindJSON(&updatedTask); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
 	task, found := database.UpdateTask(id, updatedTask)
 	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeTaskNotFound))
 		return
 	}
 
@@ -71,13 +72,13 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 func (tc *TaskController) DeleteTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
 	deleted := database.DeleteTask(id)
 	if !deleted {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeTaskNotFound))
 		return
 	}
 
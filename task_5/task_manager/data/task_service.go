@@ -2,12 +2,16 @@ package database
 
 var nextID int = 1
 
+// TaskModel is both the stored task and the request body for creating and
+// updating one. The custom binding rules are registered by the validation
+// package.
 type TaskModel struct {
-	ID          int `json:"id"`
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	DueDate     string `json:"dueDate"`
-	Status      bool `json:"status"`
+	ID          int    `json:"id"`
+	Title       string `json:"title" binding:"required,notblank,max=200"`
+	Description string `json:"description" binding:"max=2000"`
+	DueDate     string `json:"dueDate" binding:"omitempty,rfc3339,recent"`
+	Priority    string `json:"priority" binding:"omitempty,oneof=low medium high"`
+	Status      bool   `json:"status"`
 }
 
 var table []TaskModel
@@ -1,18 +1,58 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"task_manager/router"
+	"time"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish after a
+// SIGINT or SIGTERM.
+const shutdownTimeout = 20 * time.Second
+
 func main() {
-	// Setup router
-	r := router.SetupRouter()
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           router.SetupRouter(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Start server on port 8080
-	log.Println("Server starting on :8080")
-	if err := r.Run(":8080"); err != nil {
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("Server starting on :8080")
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
 		log.Fatal("Failed to start server:", err)
+	case <-ctx.Done():
+		stop()
 	}
-}
 
+	log.Println("Shutting down, waiting for in-flight requests")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Println("Error draining connections:", err)
+	}
+	if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("Server error:", err)
+	}
+
+	log.Println("Server stopped")
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problems writes the response for a request that was aborted with
+// problem.Abort. It must run before every handler.
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		problem.Write(c, toProblem(c.Errors.Last().Err))
+	}
+}
+
+// toProblem maps an error to the problem sent to the client. Anything that
+// is not already a problem becomes a 500 whose cause is only logged.
+func toProblem(err error) *problem.Problem {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p
+	}
+	log.Println("Request failed:", err)
+	return problem.New(http.StatusInternalServerError, problem.CodeInternal).WithCause(err)
+}
+
+// NoRoute answers requests for paths without a handler.
+func NoRoute(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeRouteNotFound))
+}
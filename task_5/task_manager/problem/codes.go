@@ -0,0 +1,19 @@
+package problem
+
+// Code identifies a kind of problem. Codes are part of the API: clients
+// branch on them, so a code must never be renamed or reused for something
+// else. Each one needs a title in every catalog in messages.go.
+type Code string
+
+// Malformed requests.
+const (
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeInvalidParameter Code = "invalid_parameter"
+	CodeRouteNotFound    Code = "route_not_found"
+)
+
+const (
+	CodeTaskNotFound Code = "task_not_found"
+	CodeInternal     Code = "internal_error"
+)
@@ -0,0 +1,139 @@
+package problem
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// messages is the text of every problem in one language. Details and field
+// messages may hold {placeholders}: a detail takes the problem's Params, a
+// field message takes {param} from its FieldError.
+type messages struct {
+	titles  map[Code]string
+	details map[Code]string
+	// fields is keyed by rule, or by "rule.kind" where the wording depends
+	// on whether characters or items are counted. "invalid" is the
+	// fallback for rules without a message.
+	fields map[string]string
+}
+
+// supported lists the languages with a catalog. The first is the default.
+var supported = []language.Tag{language.English, language.German}
+
+var matcher = language.NewMatcher(supported)
+
+// Negotiate picks the supported language that best fits an Accept-Language
+// header.
+func Negotiate(header string) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(header)
+	_, index, _ := matcher.Match(tags...)
+	return supported[index]
+}
+
+func (m messages) title(code Code) string {
+	if title, ok := m.titles[code]; ok {
+		return title
+	}
+	if title, ok := catalogs[language.English].titles[code]; ok {
+		return title
+	}
+	return string(code)
+}
+
+func (m messages) detail(code Code, params map[string]string) string {
+	detail, ok := m.details[code]
+	if !ok {
+		detail = catalogs[language.English].details[code]
+	}
+	return fill(detail, params)
+}
+
+func (m messages) field(fieldErr FieldError) string {
+	message, ok := m.fields[fieldErr.Code+"."+fieldErr.kind]
+	if !ok {
+		message, ok = m.fields[fieldErr.Code]
+	}
+	if !ok {
+		message = m.fields["invalid"]
+	}
+	return fill(message, map[string]string{"param": fieldErr.Param})
+}
+
+func fill(text string, params map[string]string) string {
+	for key, value := range params {
+		text = strings.ReplaceAll(text, "{"+key+"}", value)
+	}
+	return text
+}
+
+var catalogs = map[language.Tag]messages{
+	language.English: {
+		titles: map[Code]string{
+			CodeInvalidRequest:   "Malformed request body",
+			CodeValidationFailed: "Request validation failed",
+			CodeInvalidParameter: "Invalid parameter",
+			CodeRouteNotFound:    "No such endpoint",
+			CodeTaskNotFound:     "Task not found",
+			CodeInternal:         "Internal server error",
+		},
+		details: map[Code]string{
+			CodeInvalidRequest:   "The request body is not valid JSON or does not match the expected shape.",
+			CodeValidationFailed: "One or more fields are invalid; see errors.",
+			CodeInvalidParameter: "The {name} parameter is not valid.",
+		},
+		fields: map[string]string{
+			"required":    "is required",
+			"notblank":    "must not be blank",
+			"rfc3339":     "must be an RFC 3339 date such as 2025-12-31 or 2025-12-31T17:00:00Z",
+			"recent":      "must not be more than {param} years in the past",
+			"oneof":       "must be one of: {param}",
+			"max":         "must be at most {param}",
+			"max.string":  "must be at most {param} characters long",
+			"max.slice":   "must have at most {param} items",
+			"min":         "must be at least {param}",
+			"min.string":  "must be at least {param} characters long",
+			"min.slice":   "must have at least {param} items",
+			"type.string": "must be a string",
+			"type.number": "must be a number",
+			"type.bool":   "must be true or false",
+			"type.slice":  "must be an array",
+			"type.object": "must be an object",
+			"invalid":     "is invalid",
+		},
+	},
+	language.German: {
+		titles: map[Code]string{
+			CodeInvalidRequest:   "Ungültiger Anfrageinhalt",
+			CodeValidationFailed: "Validierung der Anfrage fehlgeschlagen",
+			CodeInvalidParameter: "Ungültiger Parameter",
+			CodeRouteNotFound:    "Endpunkt nicht gefunden",
+			CodeTaskNotFound:     "Aufgabe nicht gefunden",
+			CodeInternal:         "Interner Serverfehler",
+		},
+		details: map[Code]string{
+			CodeInvalidRequest:   "Der Anfrageinhalt ist kein gültiges JSON oder hat nicht die erwartete Form.",
+			CodeValidationFailed: "Mindestens ein Feld ist ungültig; siehe errors.",
+			CodeInvalidParameter: "Der Parameter {name} ist ungültig.",
+		},
+		fields: map[string]string{
+			"required":    "ist erforderlich",
+			"notblank":    "darf nicht leer sein",
+			"rfc3339":     "muss ein RFC-3339-Datum wie 2025-12-31 oder 2025-12-31T17:00:00Z sein",
+			"recent":      "darf nicht mehr als {param} Jahre zurückliegen",
+			"oneof":       "muss einer der Werte {param} sein",
+			"max":         "darf höchstens {param} sein",
+			"max.string":  "darf höchstens {param} Zeichen lang sein",
+			"max.slice":   "darf höchstens {param} Einträge haben",
+			"min":         "muss mindestens {param} sein",
+			"min.string":  "muss mindestens {param} Zeichen lang sein",
+			"min.slice":   "muss mindestens {param} Einträge haben",
+			"type.string": "muss eine Zeichenkette sein",
+			"type.number": "muss eine Zahl sein",
+			"type.bool":   "muss true oder false sein",
+			"type.slice":  "muss ein Array sein",
+			"type.object": "muss ein Objekt sein",
+			"invalid":     "ist ungültig",
+		},
+	},
+}
@@ -1,14 +1,30 @@
 package router
 
 import (
+	"log"
+	"os"
+	"strconv"
 	"task_manager/controllers"
+	"task_manager/middleware"
+	"task_manager/problem"
+	"task_manager/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRouter() *gin.Engine {
 	taskController := controllers.NewTaskController()
+	healthController := controllers.NewHealthController()
+	if err := validation.Register(dueDateYearsBack()); err != nil {
+		log.Fatal("Failed to register validation rules:", err)
+	}
+	problem.UseJSONFieldNames()
 	r := gin.Default()
+	r.Use(middleware.Problems())
+	r.NoRoute(middleware.NoRoute)
+
+	r.GET("/healthz", healthController.Healthz)
+	r.GET("/readyz", healthController.Readyz)
 
 	tasks := r.Group("/tasks")
 	{
@@ -21,3 +37,17 @@ func SetupRouter() *gin.Engine {
 
 	return r
 }
+
+// dueDateYearsBack is how many years before today a due date may lie:
+// TASK_DUE_DATE_YEARS_BACK, or 10 if it is not set.
+func dueDateYearsBack() int {
+	value := os.Getenv("TASK_DUE_DATE_YEARS_BACK")
+	if value == "" {
+		return 10
+	}
+	years, err := strconv.Atoi(value)
+	if err != nil || years <= 0 {
+		log.Fatalf("TASK_DUE_DATE_YEARS_BACK must be a positive number of years, not %q", value)
+	}
+	return years
+}
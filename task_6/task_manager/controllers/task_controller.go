@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strconv"
 	database "task_manager/data"
+	"task_manager/problem"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,24 +18,29 @@ func NewTaskController() *TaskController {
 func (tc *TaskController) CreateTask(c *gin.Context) {
 	var task database.TaskModel
 	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	createdTask, err := database.CreateTask(c.Request.Context(), task)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
-	createdTask := database.CreateTask(task)
 	c.JSON(http.StatusCreated, createdTask)
 }
 
 func (tc *TaskController) GetTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
-	task, found := database.GetTaskByID(id)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	task, err := database.GetTaskByID(c.Request.Context(), id)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
@@ -42,26 +48,31 @@ func (tc *TaskController) GetTask(c *gin.Context) {
 }
 
 func (tc *TaskController) GetAllTasks(c *gin.Context) {
-	tasks := database.GetAllTasks()
+	tasks, err := database.GetAllTasks(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, tasks)
 }
 
 func (tc *TaskController) UpdateTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
 	var updatedTask database.TaskModel
 	if err := c.ShouldBindJSON(&updatedTask); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	task, found := database.UpdateTask(id, updatedTask)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	task, err := database.UpdateTask(c.Request.Context(), id, updatedTask)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
@@ -71,13 +82,12 @@ func (tc *TaskController) UpdateTask(c *gin.Context) {
 func (tc *TaskController) DeleteTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
-	deleted := database.DeleteTask(id)
-	if !deleted {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	if err := database.DeleteTask(c.Request.Context(), id); err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
@@ -0,0 +1,18 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// Ping checks that MongoDB is reachable, for the readiness probe.
+func Ping(ctx context.Context) error {
+	return taskClient.Ping(ctx, readpref.Primary())
+}
+
+// Disconnect closes the MongoDB client once the server has stopped taking
+// requests.
+func Disconnect(ctx context.Context) error {
+	return taskClient.Disconnect(ctx)
+}
@@ -3,10 +3,12 @@ package database
 import (
 	"context"
 	"errors"
-	"log"
+	"log/slog"
 	"os"
 	"time"
 
+	"task_manager/logging"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -15,15 +17,21 @@ import (
 // TaskModel represents a task document stored in MongoDB.
 // The JSON structure is kept the same to remain backward compatible
 // with the previous in-memory implementation and existing API docs.
+// The binding rules check it as a request body; the custom ones are
+// registered by the validation package.
 type TaskModel struct {
 	ID          int    `json:"id" bson:"id"`
-	Title       string `json:"title" bson:"title"`
-	Description string `json:"description" bson:"description"`
-	DueDate     string `json:"dueDate" bson:"dueDate"`
+	Title       string `json:"title" bson:"title" binding:"required,notblank,max=200"`
+	Description string `json:"description" bson:"description" binding:"max=2000"`
+	DueDate     string `json:"dueDate" bson:"dueDate" binding:"omitempty,rfc3339,recent"`
+	Priority    string `json:"priority" bson:"priority" binding:"omitempty,oneof=low medium high"`
 	Status      bool   `json:"status" bson:"status"`
 }
 
-var taskCollection *mongo.Collection
+var (
+	taskClient     *mongo.Client
+	taskCollection *mongo.Collection
+)
 
 // init establishes a MongoDB connection using the official driver.
 // It reads the connection string from the MONGOURL or MongoURL environment variable,
@@ -37,7 +45,8 @@ func init() {
 		mongoURL = "mongodb://localhost:27017"
 	}
 
-	log.Printf("MongoDB connection URL: %s", mongoURL)
+	// Connection strings usually carry credentials.
+	slog.Info("connecting to MongoDB", "url", logging.Redact(mongoURL))
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -45,13 +54,17 @@ func init() {
 	clientOpts := options.Client().ApplyURI(mongoURL)
 	client, err := mongo.Connect(ctx, clientOpts)
 	if err != nil {
-		log.Fatalf("failed to connect to MongoDB: %v", err)
+		slog.Error("failed to connect to MongoDB", "error", err)
+		os.Exit(1)
 	}
 
 	if err := client.Ping(ctx, nil); err != nil {
-		log.Fatalf("failed to ping MongoDB: %v", err)
+		slog.Error("failed to ping MongoDB", "error", err)
+		os.Exit(1)
 	}
 
+	taskClient = client
+
 	// Use a simple default database/collection name.
 	db := client.Database("task_manager_db")
 	taskCollection = db.Collection("tasks")
@@ -74,77 +87,64 @@ func getNextID(ctx context.Context) (int, error) {
 }
 
 // GetAllTasks fetches all tasks from MongoDB.
-func GetAllTasks() []TaskModel {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func GetAllTasks(ctx context.Context) ([]TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	cursor, err := taskCollection.Find(ctx, bson.D{})
 	if err != nil {
-		log.Printf("error retrieving tasks from MongoDB: %v", err)
-		return []TaskModel{}
+		return nil, wrapError(err)
 	}
 	defer cursor.Close(ctx)
 
-	var tasks []TaskModel
-	for cursor.Next(ctx) {
-		var task TaskModel
-		if err := cursor.Decode(&task); err != nil {
-			log.Printf("error decoding task document: %v", err)
-			continue
-		}
-		tasks = append(tasks, task)
+	tasks := []TaskModel{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, wrapError(err)
 	}
 
-	if err := cursor.Err(); err != nil {
-		log.Printf("cursor error while reading tasks: %v", err)
-	}
-
-	return tasks
+	return tasks, nil
 }
 
 // GetTaskByID returns a task by its integer ID from MongoDB.
-func GetTaskByID(id int) (TaskModel, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func GetTaskByID(ctx context.Context, id int) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var task TaskModel
 	err := taskCollection.FindOne(ctx, bson.M{"id": id}).Decode(&task)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return TaskModel{}, false
+		return TaskModel{}, notFound("task")
 	}
 	if err != nil {
-		log.Printf("error fetching task with id=%d: %v", id, err)
-		return TaskModel{}, false
+		return TaskModel{}, wrapError(err)
 	}
 
-	return task, true
+	return task, nil
 }
 
 // CreateTask inserts a new task document into MongoDB.
 // It assigns an auto-increment-like integer ID to remain backward compatible.
-func CreateTask(newTask TaskModel) TaskModel {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func CreateTask(ctx context.Context, newTask TaskModel) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	nextID, err := getNextID(ctx)
 	if err != nil {
-		log.Printf("error generating next task ID: %v", err)
-		return TaskModel{}
+		return TaskModel{}, wrapError(err)
 	}
 	newTask.ID = nextID
 
 	_, err = taskCollection.InsertOne(ctx, newTask)
 	if err != nil {
-		log.Printf("error inserting task into MongoDB: %v", err)
-		return TaskModel{}
+		return TaskModel{}, wrapError(err)
 	}
 
-	return newTask
+	return newTask, nil
 }
 
 // UpdateTask updates an existing task document in MongoDB by its integer ID.
-func UpdateTask(id int, updatedDetails TaskModel) (TaskModel, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func UpdateTask(ctx context.Context, id int, updatedDetails TaskModel) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	// Ensure ID remains consistent with the path parameter.
@@ -157,26 +157,28 @@ func UpdateTask(id int, updatedDetails TaskModel) (TaskModel, bool) {
 	var updated TaskModel
 	err := taskCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return TaskModel{}, false
+		return TaskModel{}, notFound("task")
 	}
 	if err != nil {
-		log.Printf("error updating task with id=%d: %v", id, err)
-		return TaskModel{}, false
+		return TaskModel{}, wrapError(err)
 	}
 
-	return updated, true
+	return updated, nil
 }
 
 // DeleteTask removes a task document from MongoDB by its integer ID.
-func DeleteTask(id int) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func DeleteTask(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	result, err := taskCollection.DeleteOne(ctx, bson.M{"id": id})
 	if err != nil {
-		log.Printf("error deleting task with id=%d: %v", id, err)
-		return false
+		return wrapError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return notFound("task")
 	}
 
-	return result.DeletedCount > 0
+	return nil
 }
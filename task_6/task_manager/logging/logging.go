@@ -0,0 +1,114 @@
+// Package logging configures log/slog for the server: JSON or text output,
+// a level from the environment, the request ID from the context on every
+// record, and redaction of secrets.
+//
+// It is configured in init so that package-level initialisation elsewhere,
+// such as the database connection, already logs in the configured format.
+// The standard log package is routed through the same handler.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+type contextKey struct{}
+
+func init() {
+	Setup()
+}
+
+// Setup installs the default logger from LOG_LEVEL (debug, info, warn or
+// error; default info) and LOG_FORMAT (json or text; default json).
+func Setup() {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(os.Getenv("LOG_LEVEL"))); err != nil {
+		level = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	if strings.EqualFold(os.Getenv("LOG_FORMAT"), "text") {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// WithRequestID returns a copy of ctx carrying id, which is then added to
+// every record logged with that context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"mongo_url":     true,
+}
+
+var (
+	urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]+):[^@\s]*@`)
+	bearerToken    = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+)
+
+// Redact masks credentials embedded in s: passwords in connection strings
+// and URLs, and bearer tokens.
+func Redact(s string) string {
+	s = urlCredentials.ReplaceAllString(s, "$1:"+redacted+"@")
+	return bearerToken.ReplaceAllString(s, "${1}"+redacted)
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
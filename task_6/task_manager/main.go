@@ -1,18 +1,77 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	database "task_manager/data"
 	"task_manager/router"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish after a
+// SIGINT or SIGTERM.
+const shutdownTimeout = 20 * time.Second
+
 func main() {
-	// Setup router
-	r := router.SetupRouter()
+	os.Exit(run())
+}
+
+// run serves until the process is signalled and returns the exit code.
+func run() int {
+	srv := &http.Server{
+		Addr:              ":8080",
+		Handler:           router.SetupRouter(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Start server on port 8080
-	log.Println("Server starting on :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("failed to start server", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		stop()
+		slog.Info("shutting down", "timeout", shutdownTimeout.String())
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("error draining connections", "error", err)
+			exitCode = 1
+		}
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server error", "error", err)
+			exitCode = 1
+		}
 	}
-}
 
+	disconnectCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := database.Disconnect(disconnectCtx); err != nil {
+		slog.Error("error disconnecting from MongoDB", "error", err)
+	}
+
+	slog.Info("server stopped")
+	return exitCode
+}
@@ -0,0 +1,29 @@
+package problem
+
+// Code identifies a kind of problem. Codes are part of the API: clients
+// branch on them, so a code must never be renamed or reused for something
+// else. Each one needs a title in every catalog in messages.go.
+type Code string
+
+// Malformed requests.
+const (
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeInvalidParameter Code = "invalid_parameter"
+	CodeRouteNotFound    Code = "route_not_found"
+)
+
+// Errors reported by the data layer. Missing records are "<thing>_not_found".
+const (
+	CodeTaskNotFound Code = "task_not_found"
+	// CodeNotFound and CodeConflict are failures the data layer could not
+	// name, such as a duplicate key.
+	CodeNotFound Code = "not_found"
+	CodeConflict Code = "conflict"
+)
+
+// Server-side failures.
+const (
+	CodeUnavailable Code = "service_unavailable"
+	CodeInternal    Code = "internal_error"
+)
@@ -0,0 +1,102 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// UseJSONFieldNames makes the validator report fields by their JSON names,
+// which is what a client sent. Call it once at startup.
+func UseJSONFieldNames() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+}
+
+// FromBindError turns an error from ShouldBindJSON into a problem that
+// lists each invalid field. The error itself is kept only as the cause:
+// its text names Go types and struct fields.
+func FromBindError(err error) *Problem {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		p := New(http.StatusBadRequest, CodeValidationFailed).WithCause(err)
+		for _, fieldErr := range validationErrs {
+			p.Errors = append(p.Errors, FieldError{
+				Field: fieldPath(fieldErr.Namespace()),
+				Code:  fieldErr.Tag(),
+				Param: ruleParam(fieldErr),
+				kind:  kindName(fieldErr.Kind()),
+			})
+		}
+		return p
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		p := New(http.StatusBadRequest, CodeValidationFailed).WithCause(err)
+		p.Errors = []FieldError{{
+			Field: typeErr.Field,
+			Code:  "type",
+			kind:  kindName(typeErr.Type.Kind()),
+		}}
+		return p
+	}
+
+	return New(http.StatusBadRequest, CodeInvalidRequest).WithCause(err)
+}
+
+// fieldPath drops the struct name the validator puts first, so
+// "LoginRequest.username" becomes "username".
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// ruleParam returns the rule's argument as a client would see it. Rules
+// that name another field get its JSON name rather than the Go one.
+func ruleParam(fieldErr validator.FieldError) string {
+	param := fieldErr.Param()
+	switch fieldErr.Tag() {
+	case "required_without", "excluded_with":
+		if param != "" {
+			param = strings.ToLower(param[:1]) + param[1:]
+		}
+	case "oneof":
+		param = strings.Join(strings.Fields(param), ", ")
+	}
+	return param
+}
+
+func kindName(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "slice"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	}
+	return ""
+}
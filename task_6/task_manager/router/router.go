@@ -1,14 +1,31 @@
 package router
 
 import (
+	"log/slog"
+	"os"
+	"strconv"
 	"task_manager/controllers"
+	"task_manager/middleware"
+	"task_manager/problem"
+	"task_manager/validation"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRouter() *gin.Engine {
 	taskController := controllers.NewTaskController()
-	r := gin.Default()
+	healthController := controllers.NewHealthController()
+	if err := validation.Register(dueDateYearsBack()); err != nil {
+		slog.Error("failed to register validation rules", "error", err)
+		os.Exit(1)
+	}
+	problem.UseJSONFieldNames()
+	r := gin.New()
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Recovery(), middleware.Problems())
+	r.NoRoute(middleware.NoRoute)
+
+	r.GET("/healthz", healthController.Healthz)
+	r.GET("/readyz", healthController.Readyz)
 
 	tasks := r.Group("/tasks")
 	{
@@ -21,3 +38,18 @@ func SetupRouter() *gin.Engine {
 
 	return r
 }
+
+// dueDateYearsBack is how many years before today a due date may lie:
+// TASK_DUE_DATE_YEARS_BACK, or 10 if it is not set.
+func dueDateYearsBack() int {
+	value := os.Getenv("TASK_DUE_DATE_YEARS_BACK")
+	if value == "" {
+		return 10
+	}
+	years, err := strconv.Atoi(value)
+	if err != nil || years <= 0 {
+		slog.Error("TASK_DUE_DATE_YEARS_BACK must be a positive number of years", "value", value)
+		os.Exit(1)
+	}
+	return years
+}
@@ -0,0 +1,93 @@
+// Package validation holds the custom rules used in binding tags. Register
+// must run before the first request is bound.
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// dateLayouts are the RFC 3339 forms a date may take: a full date, or a
+// date and time with an offset.
+var dateLayouts = []string{"2006-01-02", time.RFC3339}
+
+// aliases maps each alias tag to the rules it stands for.
+var aliases = map[string]string{}
+
+// Register adds the custom rules to gin's validator:
+//
+//   - notblank: the string has a character other than white space.
+//   - rfc3339: the string is an RFC 3339 date, such as 2025-12-31, or
+//     date-time, such as 2025-12-31T17:00:00Z.
+//   - maxyearsago=10: the RFC 3339 date is at most that many years before
+//     the time of the request.
+//   - recent: maxyearsago with recentYears, so the bound can be configured.
+func Register(recentYears int) error {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return errors.New("validation: gin is not using go-playground/validator")
+	}
+
+	rules := map[string]validator.Func{
+		"notblank":    notBlank,
+		"rfc3339":     isRFC3339,
+		"maxyearsago": maxYearsAgo,
+	}
+	for tag, rule := range rules {
+		if err := validate.RegisterValidation(tag, rule); err != nil {
+			return fmt.Errorf("validation: registering %s: %w", tag, err)
+		}
+	}
+
+	if recentYears <= 0 {
+		return fmt.Errorf("validation: recent must allow at least one year, not %d", recentYears)
+	}
+	aliases["recent"] = fmt.Sprintf("maxyearsago=%d", recentYears)
+	for alias, tags := range aliases {
+		validate.RegisterAlias(alias, tags)
+	}
+	return nil
+}
+
+func notBlank(fl validator.FieldLevel) bool {
+	return strings.IndexFunc(fl.Field().String(), func(r rune) bool { return !unicode.IsSpace(r) }) >= 0
+}
+
+func isRFC3339(fl validator.FieldLevel) bool {
+	_, ok := parseDate(fl.Field().String())
+	return ok
+}
+
+// maxYearsAgo passes values that are not dates at all, leaving those to the
+// rfc3339 rule. The bound moves with the clock, so a date accepted today may
+// be rejected when the task is updated years later.
+func maxYearsAgo(fl validator.FieldLevel) bool {
+	date, ok := parseDate(fl.Field().String())
+	if !ok {
+		return true
+	}
+	// A malformed parameter fails every value, so the mistake in the tag
+	// shows up on the first request.
+	years, err := strconv.Atoi(fl.Param())
+	if err != nil || years < 0 {
+		return false
+	}
+	return !date.Before(time.Now().AddDate(-years, 0, 0))
+}
+
+// parseDate parses an RFC 3339 date or date-time.
+func parseDate(value string) (time.Time, bool) {
+	for _, layout := range dateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date, true
+		}
+	}
+	return time.Time{}, false
+}
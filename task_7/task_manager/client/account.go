@@ -0,0 +1,109 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	database "task_manager/data"
+	"task_manager/models"
+)
+
+func (c *Client) GetProfile(ctx context.Context) (database.UserModel, error) {
+	var user database.UserModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/me"}, &user)
+	return user, err
+}
+
+func (c *Client) UpdateProfile(ctx context.Context, req models.UpdateProfileRequest) (database.UserModel, error) {
+	var user database.UserModel
+	err := c.do(ctx, request{method: http.MethodPatch, path: "/me", body: req}, &user)
+	return user, err
+}
+
+// ChangePassword signs out every other session. The client switches to the
+// token returned for this session, and to the new password for later
+// logins.
+func (c *Client) ChangePassword(ctx context.Context, currentPassword, newPassword string) error {
+	req := models.ChangePasswordRequest{CurrentPassword: currentPassword, NewPassword: newPassword}
+	var resp models.ChangePasswordResponse
+	if err := c.do(ctx, request{method: http.MethodPost, path: "/me/password", body: req}, &resp); err != nil {
+		return err
+	}
+	c.setToken(resp.Token)
+	c.setPassword(newPassword)
+	return nil
+}
+
+func (c *Client) ResendVerification(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodPost, path: "/me/email/verification"}, nil)
+}
+
+func (c *Client) EnrollTwoFactor(ctx context.Context) (models.EnrollTwoFactorResponse, error) {
+	var resp models.EnrollTwoFactorResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/me/2fa/enroll"}, &resp)
+	return resp, err
+}
+
+// ConfirmTwoFactor enables two-factor authentication and returns the
+// recovery codes, which are not shown again.
+func (c *Client) ConfirmTwoFactor(ctx context.Context, code string) ([]string, error) {
+	var resp models.ConfirmTwoFactorResponse
+	req := models.ConfirmTwoFactorRequest{Code: code}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/me/2fa/confirm", body: req}, &resp)
+	return resp.RecoveryCodes, err
+}
+
+func (c *Client) DisableTwoFactor(ctx context.Context, password, code string) error {
+	req := models.DisableTwoFactorRequest{Password: password, Code: code}
+	return c.do(ctx, request{method: http.MethodDelete, path: "/me/2fa", body: req}, nil)
+}
+
+func (c *Client) ExportAccount(ctx context.Context) (models.AccountExport, error) {
+	var export models.AccountExport
+	err := c.do(ctx, request{method: http.MethodGet, path: "/me/export"}, &export)
+	return export, err
+}
+
+// DeleteAccount deletes the signed-in account and returns its final export.
+// The client forgets its token and credentials.
+func (c *Client) DeleteAccount(ctx context.Context, password string) (models.AccountExport, error) {
+	var resp models.DeleteAccountResponse
+	req := models.DeleteAccountRequest{Password: password}
+	if err := c.do(ctx, request{method: http.MethodDelete, path: "/me", body: req, once: true}, &resp); err != nil {
+		return models.AccountExport{}, err
+	}
+
+	c.mu.Lock()
+	c.username, c.password, c.token = "", "", ""
+	c.mu.Unlock()
+	return resp.Export, nil
+}
+
+// CreateToken issues a personal API token. The returned string is the only
+// copy of the token.
+func (c *Client) CreateToken(ctx context.Context, req models.CreateAPITokenRequest) (database.APITokenModel, string, error) {
+	var resp models.CreateAPITokenResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/me/tokens", body: req}, &resp)
+	return resp.APIToken, resp.Token, err
+}
+
+func (c *Client) ListTokens(ctx context.Context) ([]database.APITokenModel, error) {
+	var tokens []database.APITokenModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/me/tokens"}, &tokens)
+	return tokens, err
+}
+
+func (c *Client) RevokeToken(ctx context.Context, tokenID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/me/tokens/" + strconv.Itoa(tokenID)}, nil)
+}
+
+func (c *Client) ListSessions(ctx context.Context) ([]database.SessionModel, error) {
+	var sessions []database.SessionModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/me/sessions"}, &sessions)
+	return sessions, err
+}
+
+func (c *Client) RevokeSession(ctx context.Context, sessionID int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/me/sessions/" + strconv.Itoa(sessionID)}, nil)
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/models"
+)
+
+// UserQuery selects a page of GET /admin/users. Zero Page and Limit use the
+// server's defaults.
+type UserQuery struct {
+	Search string
+	Page   int
+	Limit  int
+}
+
+func (q UserQuery) values() url.Values {
+	values := url.Values{}
+	if q.Search != "" {
+		values.Set("search", q.Search)
+	}
+	setPage(values, q.Page, q.Limit)
+	return values
+}
+
+// AuditQuery selects a page of GET /admin/audit.
+type AuditQuery struct {
+	Filter database.AuditFilter
+	Page   int
+	Limit  int
+}
+
+func auditValues(filter database.AuditFilter) url.Values {
+	values := url.Values{}
+	if filter.ActorID != 0 {
+		values.Set("actorId", strconv.Itoa(filter.ActorID))
+	}
+	for key, value := range map[string]string{
+		"action":     filter.Action,
+		"targetType": filter.TargetType,
+		"targetId":   filter.TargetID,
+		"result":     filter.Result,
+	} {
+		if value != "" {
+			values.Set(key, value)
+		}
+	}
+	if !filter.From.IsZero() {
+		values.Set("from", filter.From.Format(time.RFC3339))
+	}
+	if !filter.To.IsZero() {
+		values.Set("to", filter.To.Format(time.RFC3339))
+	}
+	return values
+}
+
+func setPage(values url.Values, page, limit int) {
+	if page > 0 {
+		values.Set("page", strconv.Itoa(page))
+	}
+	if limit > 0 {
+		values.Set("limit", strconv.Itoa(limit))
+	}
+}
+
+func (c *Client) Promote(ctx context.Context, username string) error {
+	req := models.PromoteRequest{Username: username}
+	return c.do(ctx, request{method: http.MethodPost, path: "/admin/promote", body: req}, nil)
+}
+
+func (c *Client) ListUsers(ctx context.Context, query UserQuery) (models.UserPage, error) {
+	var page models.UserPage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/users", query: query.values()}, &page)
+	return page, err
+}
+
+func (c *Client) UpdateUser(ctx context.Context, id int, req models.UpdateUserRequest) (database.UserModel, error) {
+	var user database.UserModel
+	err := c.do(ctx, request{method: http.MethodPatch, path: "/admin/users/" + strconv.Itoa(id), body: req}, &user)
+	return user, err
+}
+
+// DeleteUser deletes a user and hands their tasks to reassignTo, or to the
+// signed-in admin if reassignTo is 0.
+func (c *Client) DeleteUser(ctx context.Context, id, reassignTo int) (models.DeleteUserResponse, error) {
+	query := url.Values{}
+	if reassignTo != 0 {
+		query.Set("reassignTo", strconv.Itoa(reassignTo))
+	}
+	var resp models.DeleteUserResponse
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/admin/users/" + strconv.Itoa(id), query: query}, &resp)
+	return resp, err
+}
+
+// RevokeUserSessions signs a user out everywhere and returns how many
+// sessions were revoked.
+func (c *Client) RevokeUserSessions(ctx context.Context, id int) (int64, error) {
+	var resp models.RevokeSessionsResponse
+	err := c.do(ctx, request{method: http.MethodDelete, path: "/admin/users/" + strconv.Itoa(id) + "/sessions"}, &resp)
+	return resp.Revoked, err
+}
+
+func (c *Client) GetSecurityPolicy(ctx context.Context) (database.SecurityPolicy, error) {
+	var policy database.SecurityPolicy
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/security-policy"}, &policy)
+	return policy, err
+}
+
+func (c *Client) UpdateSecurityPolicy(ctx context.Context, requireAdminTwoFactor bool) (database.SecurityPolicy, error) {
+	var policy database.SecurityPolicy
+	req := models.SecurityPolicyRequest{RequireAdminTwoFactor: &requireAdminTwoFactor}
+	err := c.do(ctx, request{method: http.MethodPut, path: "/admin/security-policy", body: req}, &policy)
+	return policy, err
+}
+
+func (c *Client) ListLockouts(ctx context.Context) ([]database.LoginAttemptModel, error) {
+	var lockouts []database.LoginAttemptModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/lockouts"}, &lockouts)
+	return lockouts, err
+}
+
+// ClearLockout lifts the lockout of an IP address if ip is set, otherwise
+// of a username.
+func (c *Client) ClearLockout(ctx context.Context, username, ip string) error {
+	query := url.Values{}
+	if username != "" {
+		query.Set("username", username)
+	}
+	if ip != "" {
+		query.Set("ip", ip)
+	}
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/lockouts", query: query}, nil)
+}
+
+func (c *Client) ListAudit(ctx context.Context, query AuditQuery) (models.AuditPage, error) {
+	values := auditValues(query.Filter)
+	setPage(values, query.Page, query.Limit)
+	var page models.AuditPage
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/audit", query: values}, &page)
+	return page, err
+}
+
+// ExportAudit streams the matching entries, oldest first, as
+// newline-delimited JSON or, if format is "csv", as CSV. The caller closes
+// the reader.
+func (c *Client) ExportAudit(ctx context.Context, filter database.AuditFilter, format string) (io.ReadCloser, error) {
+	values := auditValues(filter)
+	if format != "" {
+		values.Set("format", format)
+	}
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/admin/audit/export", query: values})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Client) VerifyAudit(ctx context.Context) (database.AuditVerification, error) {
+	var result database.AuditVerification
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/audit/verify"}, &result)
+	return result, err
+}
+
+// CreateInvitation invites someone to the instance. The returned string is
+// the invitation token, which is also emailed.
+func (c *Client) CreateInvitation(ctx context.Context, req models.InviteUserRequest) (database.InvitationModel, string, error) {
+	var resp models.CreateInvitationResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/invitations", body: req}, &resp)
+	return resp.Invitation, resp.Token, err
+}
+
+func (c *Client) ListInvitations(ctx context.Context) ([]database.InvitationModel, error) {
+	var invitations []database.InvitationModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/invitations"}, &invitations)
+	return invitations, err
+}
+
+func (c *Client) RevokeInvitation(ctx context.Context, id int) error {
+	return c.do(ctx, request{method: http.MethodDelete, path: "/admin/invitations/" + strconv.Itoa(id)}, nil)
+}
+
+func (c *Client) CreateServiceAccount(ctx context.Context, req models.CreateServiceAccountRequest) (database.UserModel, error) {
+	var user database.UserModel
+	err := c.do(ctx, request{method: http.MethodPost, path: "/admin/service-accounts", body: req}, &user)
+	return user, err
+}
+
+func (c *Client) ListServiceAccounts(ctx context.Context) ([]database.UserModel, error) {
+	var users []database.UserModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/admin/service-accounts"}, &users)
+	return users, err
+}
+
+func (c *Client) CreateServiceAccountToken(ctx context.Context, id int, req models.CreateAPITokenRequest) (database.APITokenModel, string, error) {
+	var resp models.CreateAPITokenResponse
+	path := "/admin/service-accounts/" + strconv.Itoa(id) + "/tokens"
+	err := c.do(ctx, request{method: http.MethodPost, path: path, body: req}, &resp)
+	return resp.APIToken, resp.Token, err
+}
+
+func (c *Client) ListServiceAccountTokens(ctx context.Context, id int) ([]database.APITokenModel, error) {
+	var tokens []database.APITokenModel
+	path := "/admin/service-accounts/" + strconv.Itoa(id) + "/tokens"
+	err := c.do(ctx, request{method: http.MethodGet, path: path}, &tokens)
+	return tokens, err
+}
+
+func (c *Client) RevokeServiceAccountToken(ctx context.Context, id, tokenID int) error {
+	path := "/admin/service-accounts/" + strconv.Itoa(id) + "/tokens/" + strconv.Itoa(tokenID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path}, nil)
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"task_manager/models"
+)
+
+// Register creates an account. It does not log in.
+func (c *Client) Register(ctx context.Context, req models.RegisterRequest) (models.RegisterResponse, error) {
+	var resp models.RegisterResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/auth/register", body: req, public: true}, &resp)
+	return resp, err
+}
+
+func (c *Client) AcceptInvitation(ctx context.Context, req models.AcceptInvitationRequest) (models.AcceptInvitationResponse, error) {
+	var resp models.AcceptInvitationResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: "/auth/accept-invitation", body: req, public: true}, &resp)
+	return resp, err
+}
+
+func (c *Client) ForgotPassword(ctx context.Context, email string) error {
+	req := models.ForgotPasswordRequest{Email: email}
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/forgot-password", body: req, public: true}, nil)
+}
+
+func (c *Client) ResetPassword(ctx context.Context, token, newPassword string) error {
+	req := models.ResetPasswordRequest{Token: token, NewPassword: newPassword}
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/reset-password", body: req, public: true}, nil)
+}
+
+func (c *Client) VerifyEmail(ctx context.Context, token string) error {
+	req := models.VerifyEmailRequest{Token: token}
+	return c.do(ctx, request{method: http.MethodPost, path: "/auth/verify-email", body: req, public: true}, nil)
+}
+
+// Health calls the liveness probe.
+func (c *Client) Health(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/healthz", public: true, once: true}, nil)
+}
+
+// Ready calls the readiness probe; it fails with status 503 while the server
+// cannot reach its database.
+func (c *Client) Ready(ctx context.Context) error {
+	return c.do(ctx, request{method: http.MethodGet, path: "/readyz", public: true, once: true}, nil)
+}
@@ -0,0 +1,357 @@
+// Package client is a typed Go client for the task manager API. It logs in
+// with the configured credentials when it first needs a token and again when
+// the token stops being accepted, retries idempotent requests that fail
+// with a 5xx status or a network error, and retries rate-limited requests
+// after the server's Retry-After.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"task_manager/models"
+)
+
+const (
+	defaultMaxRetries = 3
+	defaultMinBackoff = 200 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+)
+
+// ErrTwoFactorRequired is returned by a login to an account with two-factor
+// authentication when Config.TwoFactorCode is not set.
+var ErrTwoFactorRequired = errors.New("two-factor code required")
+
+// Config configures a Client. Set Username and Password to have the client
+// log in by itself, or Token to use an existing login or API token as is.
+type Config struct {
+	// BaseURL is the server's address, such as http://localhost:8080.
+	BaseURL  string
+	Username string
+	Password string
+	Token    string
+	// TwoFactorCode supplies a one-time or recovery code when a login asks
+	// for one.
+	TwoFactorCode func(ctx context.Context) (string, error)
+	// HTTPClient defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// MaxRetries is how often a failed idempotent or rate-limited request
+	// is repeated; default 3, and a negative value disables retries.
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the delay before a retry, which
+	// doubles with each attempt unless the server sends Retry-After.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// Client calls the API. It is safe for concurrent use.
+type Client struct {
+	baseURL       *url.URL
+	http          *http.Client
+	twoFactorCode func(ctx context.Context) (string, error)
+	maxRetries    int
+	minBackoff    time.Duration
+	maxBackoff    time.Duration
+
+	// mu guards the credentials and serialises logins, so concurrent
+	// requests that find the token expired log in once.
+	mu       sync.Mutex
+	username string
+	password string
+	token    string
+}
+
+func New(cfg Config) (*Client, error) {
+	base, err := url.Parse(cfg.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse base URL: %w", err)
+	}
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("base URL %q must be http or https", cfg.BaseURL)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+
+	c := &Client{
+		baseURL:       base,
+		http:          cfg.HTTPClient,
+		twoFactorCode: cfg.TwoFactorCode,
+		maxRetries:    cfg.MaxRetries,
+		minBackoff:    cfg.MinBackoff,
+		maxBackoff:    cfg.MaxBackoff,
+		username:      cfg.Username,
+		password:      cfg.Password,
+		token:         cfg.Token,
+	}
+	if c.http == nil {
+		c.http = http.DefaultClient
+	}
+	switch {
+	case c.maxRetries == 0:
+		c.maxRetries = defaultMaxRetries
+	case c.maxRetries < 0:
+		c.maxRetries = 0
+	}
+	if c.minBackoff <= 0 {
+		c.minBackoff = defaultMinBackoff
+	}
+	if c.maxBackoff < c.minBackoff {
+		c.maxBackoff = max(defaultMaxBackoff, c.minBackoff)
+	}
+	return c, nil
+}
+
+// Token returns the token the client currently sends, which is empty until
+// the first login.
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// Login signs in with username and password and keeps them to sign in again
+// when the token expires.
+func (c *Client) Login(ctx context.Context, username, password string) (models.LoginResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.username, c.password = username, password
+	return c.loginLocked(ctx)
+}
+
+func (c *Client) loginLocked(ctx context.Context) (models.LoginResponse, error) {
+	var resp models.LoginResponse
+	err := c.do(ctx, request{
+		method: http.MethodPost, path: "/auth/login", public: true,
+		body: models.LoginRequest{Username: c.username, Password: c.password},
+	}, &resp)
+	if err != nil {
+		return models.LoginResponse{}, err
+	}
+
+	if resp.TwoFactorRequired {
+		if c.twoFactorCode == nil {
+			return models.LoginResponse{}, ErrTwoFactorRequired
+		}
+		code, err := c.twoFactorCode(ctx)
+		if err != nil {
+			return models.LoginResponse{}, err
+		}
+		challenge := models.LoginTwoFactorRequest{ChallengeToken: resp.ChallengeToken, Code: code}
+		resp = models.LoginResponse{}
+		err = c.do(ctx, request{method: http.MethodPost, path: "/auth/login/2fa", public: true, body: challenge}, &resp)
+		if err != nil {
+			return models.LoginResponse{}, err
+		}
+	}
+
+	c.token = resp.Token
+	return resp, nil
+}
+
+// currentToken returns the token to send, logging in first if there is none
+// but there are credentials.
+func (c *Client) currentToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" && c.username != "" {
+		if _, err := c.loginLocked(ctx); err != nil {
+			return "", err
+		}
+	}
+	return c.token, nil
+}
+
+// refreshToken logs in again unless another request already replaced the
+// rejected token. It reports false if the client has no credentials.
+func (c *Client) refreshToken(ctx context.Context, rejected string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.username == "" {
+		return false, nil
+	}
+	if c.token != rejected {
+		return true, nil
+	}
+	_, err := c.loginLocked(ctx)
+	return err == nil, err
+}
+
+func (c *Client) setToken(token string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = token
+}
+
+func (c *Client) setPassword(password string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.username != "" {
+		c.password = password
+	}
+}
+
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	body   any
+	// public requests are sent without a token.
+	public bool
+	// once disables retries, for requests whose failure is the answer.
+	once bool
+}
+
+// do sends r and decodes the JSON response into out, if out is not nil.
+func (c *Client) do(ctx context.Context, r request, out any) error {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		_, err = io.Copy(io.Discard, resp.Body)
+		return err
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", r.method, r.path, err)
+	}
+	return nil
+}
+
+// send returns the first successful response to r; the caller closes its
+// body. A 401 for an expired or revoked token is answered by logging in
+// again once.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+	if r.body != nil {
+		var err error
+		body, err = json.Marshal(r.body)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s %s request: %w", r.method, r.path, err)
+		}
+	}
+
+	refreshed := false
+	for attempt := 0; ; attempt++ {
+		var token string
+		if !r.public {
+			var err error
+			token, err = c.currentToken(ctx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		resp, err := c.attempt(ctx, r, body, token)
+		var delay time.Duration
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !c.retryable(r, attempt) {
+				return nil, err
+			}
+			delay = c.backoff(attempt)
+		case resp.StatusCode < http.StatusBadRequest:
+			return resp, nil
+		default:
+			apiErr := readError(resp)
+			if resp.StatusCode == http.StatusUnauthorized && !r.public && !refreshed && tokenRejected(apiErr) {
+				refreshed = true
+				ok, err := c.refreshToken(ctx, token)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					attempt--
+					continue
+				}
+			}
+			// A rate-limited request was not processed, so it can be
+			// repeated whatever its method. Login lockouts, also 429, last
+			// minutes and are returned.
+			limited := apiErr.Code == "rate_limited" && !r.once && attempt < c.maxRetries
+			failed := resp.StatusCode >= http.StatusInternalServerError && c.retryable(r, attempt)
+			if !limited && !failed {
+				return nil, apiErr
+			}
+			delay = retryAfter(resp)
+			if delay == 0 {
+				delay = c.backoff(attempt)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, r request, body []byte, token string) (*http.Response, error) {
+	target := *c.baseURL
+	target.Path += r.path
+	target.RawQuery = r.query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.http.Do(req)
+}
+
+// retryable reports whether r may be sent again. Only methods that are safe
+// to repeat are retried, since a failed POST may still have taken effect.
+func (c *Client) retryable(r request, attempt int) bool {
+	if r.once || attempt >= c.maxRetries {
+		return false
+	}
+	switch r.method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// backoff doubles from MinBackoff up to MaxBackoff, with jitter so clients
+// that failed together do not retry together.
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.maxBackoff
+	if attempt < 30 {
+		delay = min(c.minBackoff<<attempt, c.maxBackoff)
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func tokenRejected(err *Error) bool {
+	return err.Code == "invalid_token" || err.Code == "session_expired"
+}
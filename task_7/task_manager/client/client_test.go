@@ -0,0 +1,426 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/models"
+)
+
+// hangUp as a reply status closes the connection without an answer.
+const hangUp = -1
+
+// reply is a queued answer of fakeAPI.
+type reply struct {
+	status     int
+	code       string
+	retryAfter string
+}
+
+// fakeAPI stands in for the server in tests of the client's own logic. It
+// logs in anyone, handing out token-1, token-2 and so on, and answers other
+// requests with the replies queued for their route, then with the route's
+// handler or 200 and {}. It records the token sent with every request.
+type fakeAPI struct {
+	mu       sync.Mutex
+	logins   int
+	queued   map[string][]reply
+	handlers map[string]http.HandlerFunc
+	tokens   map[string][]string
+}
+
+func (f *fakeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := r.Method + " " + r.URL.Path
+
+	f.mu.Lock()
+	f.tokens[route] = append(f.tokens[route], strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	var next reply
+	if queue := f.queued[route]; len(queue) > 0 {
+		next, f.queued[route] = queue[0], queue[1:]
+	}
+	handler := f.handlers[route]
+	f.mu.Unlock()
+
+	switch {
+	case next.status == hangUp:
+		panic(http.ErrAbortHandler)
+	case next.status != 0:
+		if next.retryAfter != "" {
+			w.Header().Set("Retry-After", next.retryAfter)
+		}
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(next.status)
+		json.NewEncoder(w).Encode(Error{Status: next.status, Code: next.code})
+	case route == "POST /auth/login":
+		f.mu.Lock()
+		f.logins++
+		token := fmt.Sprintf("token-%d", f.logins)
+		f.mu.Unlock()
+		writeJSON(w, models.LoginResponse{Token: token})
+	case handler != nil:
+		handler(w, r)
+	default:
+		writeJSON(w, struct{}{})
+	}
+}
+
+func writeJSON(w http.ResponseWriter, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// queue makes the next requests to route get replies, in order.
+func (f *fakeAPI) queue(route string, replies ...reply) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queued[route] = replies
+}
+
+// sent returns the tokens of the requests to route so far, one per request.
+func (f *fakeAPI) sent(route string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return slices.Clone(f.tokens[route])
+}
+
+// newFake starts a fakeAPI and a client for it that logs in as ivy unless
+// cfg brings its own credentials. The backoff is short, so a retry that
+// waits seconds waited for Retry-After.
+func newFake(t *testing.T, cfg Config) (*fakeAPI, *Client) {
+	t.Helper()
+	f := &fakeAPI{queued: map[string][]reply{}, handlers: map[string]http.HandlerFunc{}, tokens: map[string][]string{}}
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	cfg.BaseURL = server.URL
+	if cfg.Username == "" && cfg.Token == "" {
+		cfg.Username, cfg.Password = "ivy", password
+	}
+	// A reused connection that is closed without an answer would be retried
+	// by the transport itself, hiding the client's own retry.
+	cfg.HTTPClient = &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 10 * time.Second}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff, cfg.MaxBackoff = time.Millisecond, 2*time.Millisecond
+	}
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f, c
+}
+
+func TestSendLogsInBeforeTheFirstRequest(t *testing.T) {
+	f, c := newFake(t, Config{})
+	ctx := context.Background()
+
+	for range 2 {
+		if _, err := c.GetProfile(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if logins := f.sent("POST /auth/login"); !slices.Equal(logins, []string{""}) {
+		t.Errorf("logins sent tokens %q, want one login without a token", logins)
+	}
+	if got, want := f.sent("GET /me"), []string{"token-1", "token-1"}; !slices.Equal(got, want) {
+		t.Errorf("GET /me sent %q, want %q", got, want)
+	}
+}
+
+func TestSendLogsInAgainWhenTheTokenIsRejected(t *testing.T) {
+	tests := []struct {
+		code string
+		// relogin is whether the client logs in again and repeats the
+		// request.
+		relogin bool
+	}{
+		{code: "invalid_token", relogin: true},
+		{code: "session_expired", relogin: true},
+		{code: "invalid_credentials", relogin: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			f, c := newFake(t, Config{})
+			f.queue("GET /me", reply{status: http.StatusUnauthorized, code: tt.code})
+
+			_, err := c.GetProfile(context.Background())
+			got := f.sent("GET /me")
+			if !tt.relogin {
+				var apiErr *Error
+				if !errors.As(err, &apiErr) || apiErr.Code != tt.code {
+					t.Fatalf("GetProfile = %v, want %s", err, tt.code)
+				}
+				if !slices.Equal(got, []string{"token-1"}) {
+					t.Errorf("GET /me sent %q, want one request", got)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"token-1", "token-2"}; !slices.Equal(got, want) {
+				t.Errorf("GET /me sent %q, want %q", got, want)
+			}
+			if c.Token() != "token-2" {
+				t.Errorf("token = %q, want the new login's", c.Token())
+			}
+		})
+	}
+}
+
+func TestSendLogsInAgainWithoutUsingUpARetry(t *testing.T) {
+	f, c := newFake(t, Config{MaxRetries: 1})
+	f.queue("GET /me",
+		reply{status: http.StatusUnauthorized, code: "invalid_token"},
+		reply{status: http.StatusServiceUnavailable})
+
+	if _, err := c.GetProfile(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := f.sent("GET /me"), []string{"token-1", "token-2", "token-2"}; !slices.Equal(got, want) {
+		t.Errorf("GET /me sent %q, want %q", got, want)
+	}
+}
+
+func TestSendLogsInAgainOnlyOnce(t *testing.T) {
+	f, c := newFake(t, Config{})
+	f.queue("GET /me",
+		reply{status: http.StatusUnauthorized, code: "invalid_token"},
+		reply{status: http.StatusUnauthorized, code: "invalid_token"})
+
+	var apiErr *Error
+	if _, err := c.GetProfile(context.Background()); !errors.As(err, &apiErr) || apiErr.Status != http.StatusUnauthorized {
+		t.Fatalf("GetProfile = %v, want 401", err)
+	}
+	if logins := f.sent("POST /auth/login"); len(logins) != 2 {
+		t.Errorf("%d logins, want 2", len(logins))
+	}
+}
+
+func TestSendReturnsARejectedTokenWithoutCredentials(t *testing.T) {
+	f, c := newFake(t, Config{Token: "api-token"})
+	f.queue("GET /me", reply{status: http.StatusUnauthorized, code: "invalid_token"})
+
+	var apiErr *Error
+	if _, err := c.GetProfile(context.Background()); !errors.As(err, &apiErr) || apiErr.Code != "invalid_token" {
+		t.Fatalf("GetProfile = %v, want invalid_token", err)
+	}
+	if logins := f.sent("POST /auth/login"); len(logins) != 0 {
+		t.Errorf("%d logins without credentials", len(logins))
+	}
+}
+
+func TestSendRetriesServerErrorsOnlyForIdempotentMethods(t *testing.T) {
+	tests := []struct {
+		method   string
+		attempts int
+	}{
+		{http.MethodGet, 3},
+		{http.MethodPut, 3},
+		{http.MethodDelete, 3},
+		// A failed POST or PATCH may still have taken effect.
+		{http.MethodPost, 1},
+		{http.MethodPatch, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			f, c := newFake(t, Config{})
+			route := tt.method + " /things"
+			f.queue(route, reply{status: http.StatusBadGateway}, reply{status: hangUp})
+
+			err := c.do(context.Background(), request{method: tt.method, path: "/things"}, nil)
+			if got := len(f.sent(route)); got != tt.attempts {
+				t.Errorf("%d attempts, want %d", got, tt.attempts)
+			}
+			var apiErr *Error
+			if tt.attempts > 1 && err != nil {
+				t.Errorf("error after the retries: %v", err)
+			}
+			if tt.attempts == 1 && (!errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway) {
+				t.Errorf("error = %v, want 502", err)
+			}
+		})
+	}
+}
+
+func TestSendStopsAfterMaxRetries(t *testing.T) {
+	tests := []struct {
+		maxRetries int
+		attempts   int
+	}{
+		{maxRetries: 0, attempts: 1 + defaultMaxRetries},
+		{maxRetries: 1, attempts: 2},
+		{maxRetries: -1, attempts: 1},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.maxRetries), func(t *testing.T) {
+			f, c := newFake(t, Config{MaxRetries: tt.maxRetries})
+			f.queue("GET /me", slices.Repeat([]reply{{status: http.StatusServiceUnavailable}}, 10)...)
+
+			var apiErr *Error
+			if _, err := c.GetProfile(context.Background()); !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+				t.Fatalf("GetProfile = %v, want 503", err)
+			}
+			if got := len(f.sent("GET /me")); got != tt.attempts {
+				t.Errorf("%d attempts, want %d", got, tt.attempts)
+			}
+		})
+	}
+}
+
+func TestSendWaitsForRetryAfterWhenRateLimited(t *testing.T) {
+	f, c := newFake(t, Config{})
+	ctx := context.Background()
+
+	// A rate-limited request was not processed, so even a POST is repeated,
+	// after Retry-After rather than the millisecond backoff.
+	f.queue("POST /workspaces", reply{status: http.StatusTooManyRequests, code: "rate_limited", retryAfter: "1"})
+	started := time.Now()
+	if _, err := c.CreateWorkspace(ctx, "limited"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed < time.Second {
+		t.Errorf("retried after %v, want Retry-After's second", elapsed)
+	}
+	if got := len(f.sent("POST /workspaces")); got != 2 {
+		t.Errorf("%d attempts, want 2", got)
+	}
+
+	// Without Retry-After the backoff applies.
+	f.queue("POST /workspaces", reply{status: http.StatusTooManyRequests, code: "rate_limited"})
+	started = time.Now()
+	if _, err := c.CreateWorkspace(ctx, "limited"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("retried after %v, want the backoff", elapsed)
+	}
+}
+
+func TestSendReturnsLoginLockouts(t *testing.T) {
+	f, c := newFake(t, Config{})
+
+	// A lockout lasts minutes, so it is returned rather than waited out,
+	// although it is also a 429 with Retry-After.
+	f.queue("POST /auth/login", reply{status: http.StatusTooManyRequests, code: "too_many_attempts", retryAfter: "60"})
+	started := time.Now()
+	var apiErr *Error
+	if _, err := c.Login(context.Background(), "ivy", "wrong-password"); !errors.As(err, &apiErr) || apiErr.Code != "too_many_attempts" {
+		t.Fatalf("Login = %v, want too_many_attempts", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("Login took %v, want it returned at once", elapsed)
+	}
+	if got := len(f.sent("POST /auth/login")); got != 1 {
+		t.Errorf("%d attempts, want 1", got)
+	}
+}
+
+func TestSendDoesNotRepeatRequestsSentOnce(t *testing.T) {
+	for _, failure := range []reply{
+		{status: http.StatusServiceUnavailable},
+		{status: http.StatusTooManyRequests, code: "rate_limited"},
+	} {
+		t.Run(strconv.Itoa(failure.status), func(t *testing.T) {
+			f, c := newFake(t, Config{})
+			f.queue("GET /healthz", failure)
+
+			if err := c.Health(context.Background()); err == nil {
+				t.Fatal("Health succeeded, want the failure")
+			}
+			if got := len(f.sent("GET /healthz")); got != 1 {
+				t.Errorf("%d attempts, want 1", got)
+			}
+		})
+	}
+}
+
+func TestUsersFetchesPagesUntilTheTotal(t *testing.T) {
+	var all []database.UserModel
+	var want []string
+	for i := range 5 {
+		username := "paged-" + string(rune('a'+i))
+		all = append(all, database.UserModel{ID: i + 1, Username: username})
+		want = append(want, username)
+	}
+	serve := func(w http.ResponseWriter, r *http.Request) {
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		from := min((page-1)*limit, len(all))
+		to := min(from+limit, len(all))
+		writeJSON(w, models.UserPage{Users: all[from:to], Total: int64(len(all)), Page: page, Limit: limit})
+	}
+
+	tests := []struct {
+		limit    int
+		requests int
+	}{
+		{limit: 2, requests: 3},
+		// A full last page ends the iteration without asking for the next.
+		{limit: 5, requests: 1},
+		{limit: 10, requests: 1},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.limit), func(t *testing.T) {
+			f, c := newFake(t, Config{})
+			f.handlers["GET /admin/users"] = serve
+
+			var got []string
+			for user, err := range c.Users(context.Background(), UserQuery{Limit: tt.limit}) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, user.Username)
+			}
+
+			if !slices.Equal(got, want) {
+				t.Errorf("users = %v, want %v", got, want)
+			}
+			if requests := len(f.sent("GET /admin/users")); requests != tt.requests {
+				t.Errorf("%d requests, want %d", requests, tt.requests)
+			}
+		})
+	}
+
+	t.Run("break", func(t *testing.T) {
+		f, c := newFake(t, Config{})
+		f.handlers["GET /admin/users"] = serve
+		for range c.Users(context.Background(), UserQuery{Limit: 2}) {
+			break
+		}
+		if requests := len(f.sent("GET /admin/users")); requests != 1 {
+			t.Errorf("breaking after the first user made %d requests, want 1", requests)
+		}
+	})
+
+	t.Run("error", func(t *testing.T) {
+		f, c := newFake(t, Config{})
+		f.handlers["GET /admin/users"] = serve
+		f.queue("GET /admin/users", reply{}, reply{status: http.StatusForbidden, code: "admin_required"})
+
+		var users int
+		var errs []error
+		for _, err := range c.Users(context.Background(), UserQuery{Limit: 2}) {
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			users++
+		}
+		if users != 2 || len(errs) != 1 {
+			t.Errorf("%d users and errors %v, want the first page and one error", users, errs)
+		}
+	})
+}
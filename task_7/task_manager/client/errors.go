@@ -0,0 +1,62 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Error is a failed request, decoded from the server's problem details.
+// Code is the stable error code, such as "task_not_found"; the titles and
+// details are localized and should only be shown to people.
+type Error struct {
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Title     string       `json:"title"`
+	Detail    string       `json:"detail"`
+	RequestID string       `json:"requestId"`
+	Errors    []FieldError `json:"errors"`
+}
+
+// FieldError is one invalid field of a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d", e.Status)
+	if e.Code != "" {
+		fmt.Fprintf(&b, " %s", e.Code)
+	}
+	if e.Detail != "" {
+		fmt.Fprintf(&b, ": %s", e.Detail)
+	} else if e.Title != "" {
+		fmt.Fprintf(&b, ": %s", e.Title)
+	}
+	for _, field := range e.Errors {
+		fmt.Fprintf(&b, "; %s: %s", field.Field, field.Message)
+	}
+	return b.String()
+}
+
+// readError decodes and closes the body of a failed response. Bodies that
+// are not problem details, such as from a proxy, keep only the status.
+func readError(resp *http.Response) *Error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if strings.Contains(resp.Header.Get("Content-Type"), "json") {
+		_ = json.Unmarshal(data, apiErr)
+	}
+	apiErr.Status = resp.StatusCode
+	if apiErr.Title == "" {
+		apiErr.Title = http.StatusText(resp.StatusCode)
+	}
+	return apiErr
+}
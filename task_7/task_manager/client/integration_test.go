@@ -0,0 +1,380 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"task_manager/config"
+	database "task_manager/data"
+	"task_manager/router"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const password = "Correct-Horse-Battery-9"
+
+// api is the server with a hook in front of it. It records the status of
+// every request and can answer one itself, to fake the server errors the API
+// cannot be made to produce. Requests name the client IP they come from.
+type api struct {
+	handler http.Handler
+
+	mu       sync.Mutex
+	statuses map[string][]int
+	faults   map[string][]int
+}
+
+// key identifies requests from one test to one route.
+func key(ip, method, path string) string {
+	return ip + " " + method + " " + path
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ip := r.Header.Get("X-Test-IP")
+	r.RemoteAddr = ip + ":1234"
+	k := key(ip, r.Method, r.URL.Path)
+
+	a.mu.Lock()
+	status := 0
+	if faults := a.faults[k]; len(faults) > 0 {
+		status, a.faults[k] = faults[0], faults[1:]
+	}
+	a.mu.Unlock()
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	if status != 0 {
+		http.Error(recorder, http.StatusText(status), status)
+	} else {
+		a.handler.ServeHTTP(recorder, r)
+	}
+
+	a.mu.Lock()
+	a.statuses[k] = append(a.statuses[k], recorder.status)
+	a.mu.Unlock()
+}
+
+// fail answers the next requests to method and path with statuses; a zero
+// status lets that request through.
+func (a *api) fail(ip, method, path string, statuses ...int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.faults[key(ip, method, path)] = statuses
+}
+
+// seen returns the statuses of the requests to method and path so far.
+func (a *api) seen(ip, method, path string) []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return slices.Clone(a.statuses[key(ip, method, path)])
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// server and baseURL serve the current test.
+var (
+	server  *api
+	baseURL string
+)
+
+// start runs the API against a throwaway database on TEST_MONGO_URL, dropped
+// when the test ends. Rate limiting is on, as in production. These tests
+// check the client against the real server; client_test.go covers its
+// retry and login logic without a database.
+func start(t *testing.T) {
+	t.Helper()
+	mongoURL := os.Getenv("TEST_MONGO_URL")
+	if mongoURL == "" {
+		t.Skip("TEST_MONGO_URL is not set")
+	}
+
+	cfg := config.Default()
+	cfg.Database.URL = mongoURL
+	cfg.Database.Name = "task_manager_client_" + strings.ToLower(rand.Text()[:8])
+	cfg.Auth.JWTSecret = "client-test-secret-0123456789abcdef"
+	cfg.RateLimit.Enabled = true
+
+	ctx := context.Background()
+	if err := database.Connect(ctx, cfg.Database); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+		if err == nil {
+			client.Database(cfg.Database.Name).Drop(ctx)
+			client.Disconnect(ctx)
+		}
+		database.Disconnect(ctx)
+	})
+
+	gin.SetMode(gin.TestMode)
+	server = &api{handler: router.SetupRouter(cfg), statuses: map[string][]int{}, faults: map[string][]int{}}
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+	baseURL = httpServer.URL
+}
+
+// ipTransport sends every request from ip, as far as the API can tell.
+type ipTransport string
+
+func (ip ipTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Test-IP", string(ip))
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newClient creates a user and a client that logs in as it from ip. The
+// backoff is short, so a retry that waits seconds waited for Retry-After.
+func newClient(t *testing.T, ip, username string, cfg Config) (*Client, database.UserModel) {
+	t.Helper()
+	user, err := database.CreateUser(context.Background(), username, password, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.BaseURL = baseURL
+	cfg.Username, cfg.Password = username, password
+	cfg.HTTPClient = &http.Client{Transport: ipTransport(ip), Timeout: 10 * time.Second}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff, cfg.MaxBackoff = time.Millisecond, 2*time.Millisecond
+	}
+	c, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, user
+}
+
+func TestLogsInWhenFirstNeeded(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.1"
+	c, _ := newClient(t, ip, "ivy", Config{})
+
+	if c.Token() != "" {
+		t.Fatal("the client logged in before the first request")
+	}
+	for range 2 {
+		profile, err := c.GetProfile(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if profile.Username != "ivy" {
+			t.Fatalf("profile of %s, want ivy", profile.Username)
+		}
+	}
+
+	if logins := server.seen(ip, http.MethodPost, "/auth/login"); !slices.Equal(logins, []int{http.StatusOK}) {
+		t.Errorf("logins = %v, want one", logins)
+	}
+	if c.Token() == "" {
+		t.Error("no token after logging in")
+	}
+}
+
+func TestLogsInAgainWhenTheSessionIsRevoked(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.2"
+	c, user := newClient(t, ip, "jon", Config{MaxRetries: 1})
+
+	if _, err := c.GetProfile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	revoked := c.Token()
+	if _, err := database.RevokeAllSessions(ctx, user.ID, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request after the new login fails once more. Logging in again
+	// must not use up the single retry.
+	server.fail(ip, http.MethodGet, "/me", 0, http.StatusServiceUnavailable)
+	if _, err := c.GetProfile(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []int{http.StatusOK, http.StatusUnauthorized, http.StatusServiceUnavailable, http.StatusOK}
+	if got := server.seen(ip, http.MethodGet, "/me"); !slices.Equal(got, want) {
+		t.Errorf("GET /me = %v, want %v", got, want)
+	}
+	if logins := server.seen(ip, http.MethodPost, "/auth/login"); len(logins) != 2 {
+		t.Errorf("logins = %v, want two", logins)
+	}
+	if c.Token() == revoked {
+		t.Error("the client kept the revoked token")
+	}
+}
+
+func TestRetriesServerErrorsOnlyForIdempotentRequests(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.3"
+	c, _ := newClient(t, ip, "kim", Config{})
+
+	server.fail(ip, http.MethodGet, "/workspaces", http.StatusBadGateway, http.StatusServiceUnavailable)
+	if _, err := c.ListWorkspaces(ctx); err != nil {
+		t.Fatalf("GET after two failures: %v", err)
+	}
+	if got := server.seen(ip, http.MethodGet, "/workspaces"); len(got) != 3 {
+		t.Errorf("GET /workspaces = %v, want two failures and a success", got)
+	}
+
+	server.fail(ip, http.MethodGet, "/workspaces", slices.Repeat([]int{http.StatusServiceUnavailable}, 4)...)
+	var apiErr *Error
+	if _, err := c.ListWorkspaces(ctx); !errors.As(err, &apiErr) || apiErr.Status != http.StatusServiceUnavailable {
+		t.Fatalf("GET failing on every attempt = %v, want 503", err)
+	}
+	if got := server.seen(ip, http.MethodGet, "/workspaces"); len(got) != 3+1+defaultMaxRetries {
+		t.Errorf("GET /workspaces = %v, want %d more attempts", got, 1+defaultMaxRetries)
+	}
+
+	// A failed POST may have taken effect, so it is not repeated.
+	server.fail(ip, http.MethodPost, "/workspaces", http.StatusInternalServerError)
+	if _, err := c.CreateWorkspace(ctx, "once"); !errors.As(err, &apiErr) || apiErr.Status != http.StatusInternalServerError {
+		t.Fatalf("POST = %v, want 500", err)
+	}
+	if got := server.seen(ip, http.MethodPost, "/workspaces"); len(got) != 1 {
+		t.Errorf("POST /workspaces = %v, want one attempt", got)
+	}
+}
+
+func TestRateLimitedRequestsWaitForRetryAfter(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.4"
+	c, _ := newClient(t, ip, "lea", Config{})
+
+	// /me allows a burst of 30 requests, then two a second. With a backoff
+	// of milliseconds, only waiting for Retry-After gets through.
+	started := time.Now()
+	for i := range 35 {
+		if _, err := c.GetProfile(ctx); err != nil {
+			t.Fatalf("request %d: %v", i+1, err)
+		}
+	}
+
+	got := server.seen(ip, http.MethodGet, "/me")
+	limited := 0
+	for i, status := range got {
+		if status == http.StatusTooManyRequests {
+			limited++
+			if i+1 < len(got) && got[i+1] == http.StatusTooManyRequests {
+				t.Fatalf("GET /me = %v: a retry came before Retry-After", got)
+			}
+		}
+	}
+	if limited == 0 {
+		t.Fatalf("GET /me = %v, want the rate limit to be reached", got)
+	}
+	if elapsed := time.Since(started); elapsed < time.Duration(limited)*time.Second {
+		t.Errorf("%d rate-limited requests took %v, want a second each", limited, elapsed)
+	}
+}
+
+func TestLoginLockoutIsNotRetried(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.5"
+	c, _ := newClient(t, ip, "max", Config{})
+
+	// A lockout lasts minutes, so it is returned rather than waited out,
+	// although it is also a 429 with Retry-After.
+	var apiErr *Error
+	logins := 0
+	for {
+		logins++
+		_, err := c.Login(ctx, "max", "wrong-password")
+		if errors.As(err, &apiErr) && apiErr.Code == "too_many_attempts" {
+			break
+		}
+		if logins == 10 {
+			t.Fatalf("no lockout after %d failed logins: %v", logins, err)
+		}
+	}
+
+	started := time.Now()
+	logins++
+	if _, err := c.Login(ctx, "max", password); !errors.As(err, &apiErr) || apiErr.Code != "too_many_attempts" {
+		t.Fatalf("login while locked out = %v, want too_many_attempts", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Errorf("login while locked out took %v, want it returned at once", elapsed)
+	}
+	if got := server.seen(ip, http.MethodPost, "/auth/login"); len(got) != logins {
+		t.Errorf("POST /auth/login = %v, want %d requests, one per login", got, logins)
+	}
+}
+
+func TestIteratesOverEveryPage(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+	ip := "10.0.0.6"
+	c, _ := newClient(t, ip, "nia", Config{})
+	if err := database.PromoteUser(ctx, "nia"); err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for i := range 5 {
+		username := "paged-" + string(rune('a'+i))
+		if _, err := database.CreateUser(ctx, username, password, ""); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, username)
+	}
+
+	tests := []struct {
+		limit    int
+		requests int
+	}{
+		{limit: 2, requests: 3},
+		// A full last page ends the iteration without asking for the next.
+		{limit: 5, requests: 1},
+		{limit: 10, requests: 1},
+	}
+	for _, tt := range tests {
+		before := len(server.seen(ip, http.MethodGet, "/admin/users"))
+
+		var got []string
+		for user, err := range c.Users(ctx, UserQuery{Search: "paged-", Limit: tt.limit}) {
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, user.Username)
+		}
+
+		if !slices.Equal(got, want) {
+			t.Errorf("limit %d: users = %v, want %v", tt.limit, got, want)
+		}
+		if requests := len(server.seen(ip, http.MethodGet, "/admin/users")) - before; requests != tt.requests {
+			t.Errorf("limit %d: %d requests, want %d", tt.limit, requests, tt.requests)
+		}
+	}
+
+	// Breaking out of the loop stops fetching.
+	before := len(server.seen(ip, http.MethodGet, "/admin/users"))
+	for range c.Users(ctx, UserQuery{Search: "paged-", Limit: 2}) {
+		break
+	}
+	if requests := len(server.seen(ip, http.MethodGet, "/admin/users")) - before; requests != 1 {
+		t.Errorf("breaking after the first user made %d requests, want 1", requests)
+	}
+}
@@ -0,0 +1,57 @@
+package client
+
+import (
+	"context"
+	"iter"
+
+	database "task_manager/data"
+)
+
+// Users iterates over every user matching query, fetching one page at a
+// time from query.Page on. Iteration stops after the first error.
+func (c *Client) Users(ctx context.Context, query UserQuery) iter.Seq2[database.UserModel, error] {
+	return func(yield func(database.UserModel, error) bool) {
+		query.Page = max(query.Page, 1)
+		for {
+			page, err := c.ListUsers(ctx, query)
+			if err != nil {
+				yield(database.UserModel{}, err)
+				return
+			}
+			for _, user := range page.Users {
+				if !yield(user, nil) {
+					return
+				}
+			}
+			if len(page.Users) == 0 || int64(page.Page*page.Limit) >= page.Total {
+				return
+			}
+			query.Page++
+		}
+	}
+}
+
+// AuditEntries iterates over every audit entry matching query, newest first.
+// Entries recorded while iterating shift the pages, so an entry may be seen
+// twice; use ExportAudit for a consistent copy.
+func (c *Client) AuditEntries(ctx context.Context, query AuditQuery) iter.Seq2[database.AuditEntryModel, error] {
+	return func(yield func(database.AuditEntryModel, error) bool) {
+		query.Page = max(query.Page, 1)
+		for {
+			page, err := c.ListAudit(ctx, query)
+			if err != nil {
+				yield(database.AuditEntryModel{}, err)
+				return
+			}
+			for _, entry := range page.Entries {
+				if !yield(entry, nil) {
+					return
+				}
+			}
+			if len(page.Entries) == 0 || int64(page.Page*page.Limit) >= page.Total {
+				return
+			}
+			query.Page++
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	database "task_manager/data"
+	"task_manager/models"
+)
+
+func workspacePath(workspaceID int) string {
+	return "/workspaces/" + strconv.Itoa(workspaceID)
+}
+
+func (c *Client) CreateWorkspace(ctx context.Context, name string) (database.WorkspaceModel, error) {
+	var workspace database.WorkspaceModel
+	req := models.CreateWorkspaceRequest{Name: name}
+	err := c.do(ctx, request{method: http.MethodPost, path: "/workspaces", body: req}, &workspace)
+	return workspace, err
+}
+
+// ListWorkspaces returns the workspaces the signed-in user belongs to.
+func (c *Client) ListWorkspaces(ctx context.Context) ([]database.WorkspaceModel, error) {
+	var workspaces []database.WorkspaceModel
+	err := c.do(ctx, request{method: http.MethodGet, path: "/workspaces"}, &workspaces)
+	return workspaces, err
+}
+
+func (c *Client) GetWorkspace(ctx context.Context, workspaceID int) (database.WorkspaceModel, error) {
+	var workspace database.WorkspaceModel
+	err := c.do(ctx, request{method: http.MethodGet, path: workspacePath(workspaceID)}, &workspace)
+	return workspace, err
+}
+
+func (c *Client) AddMember(ctx context.Context, workspaceID int, req models.AddWorkspaceMemberRequest) (database.WorkspaceMember, error) {
+	var member database.WorkspaceMember
+	err := c.do(ctx, request{method: http.MethodPost, path: workspacePath(workspaceID) + "/members", body: req}, &member)
+	return member, err
+}
+
+func (c *Client) RemoveMember(ctx context.Context, workspaceID, userID int) error {
+	path := workspacePath(workspaceID) + "/members/" + strconv.Itoa(userID)
+	return c.do(ctx, request{method: http.MethodDelete, path: path}, nil)
+}
+
+// CreateWorkspaceInvitation invites someone to a workspace. The returned
+// string is the invitation token, which is also emailed.
+func (c *Client) CreateWorkspaceInvitation(ctx context.Context, workspaceID int, req models.InviteWorkspaceMemberRequest) (database.InvitationModel, string, error) {
+	var resp models.CreateInvitationResponse
+	err := c.do(ctx, request{method: http.MethodPost, path: workspacePath(workspaceID) + "/invitations", body: req}, &resp)
+	return resp.Invitation, resp.Token, err
+}
+
+func (c *Client) ListWorkspaceInvitations(ctx context.Context, workspaceID int) ([]database.InvitationModel, error) {
+	var invitations []database.InvitationModel
+	err := c.do(ctx, request{method: http.MethodGet, path: workspacePath(workspaceID) + "/invitations"}, &invitations)
+	return invitations, err
+}
+
+func (c *Client) RevokeWorkspaceInvitation(ctx context.Context, workspaceID, id int) error {
+	path := workspacePath(workspaceID) + "/invitations/" + strconv.Itoa(id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path}, nil)
+}
+
+func (c *Client) ListTasks(ctx context.Context, workspaceID int) ([]database.TaskModel, error) {
+	var tasks []database.TaskModel
+	err := c.do(ctx, request{method: http.MethodGet, path: workspacePath(workspaceID) + "/tasks"}, &tasks)
+	return tasks, err
+}
+
+func (c *Client) GetTask(ctx context.Context, workspaceID, id int) (database.TaskModel, error) {
+	var task database.TaskModel
+	path := workspacePath(workspaceID) + "/tasks/" + strconv.Itoa(id)
+	err := c.do(ctx, request{method: http.MethodGet, path: path}, &task)
+	return task, err
+}
+
+// CreateTask creates task in a workspace; its ID, workspace and owner are
+// set by the server.
+func (c *Client) CreateTask(ctx context.Context, workspaceID int, task database.TaskModel) (database.TaskModel, error) {
+	var created database.TaskModel
+	err := c.do(ctx, request{method: http.MethodPost, path: workspacePath(workspaceID) + "/tasks", body: task}, &created)
+	return created, err
+}
+
+// UpdateTask replaces every field of a task.
+func (c *Client) UpdateTask(ctx context.Context, workspaceID, id int, task database.TaskModel) (database.TaskModel, error) {
+	var updated database.TaskModel
+	path := workspacePath(workspaceID) + "/tasks/" + strconv.Itoa(id)
+	err := c.do(ctx, request{method: http.MethodPut, path: path, body: task}, &updated)
+	return updated, err
+}
+
+func (c *Client) DeleteTask(ctx context.Context, workspaceID, id int) error {
+	path := workspacePath(workspaceID) + "/tasks/" + strconv.Itoa(id)
+	return c.do(ctx, request{method: http.MethodDelete, path: path}, nil)
+}
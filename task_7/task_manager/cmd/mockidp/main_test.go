@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"task_manager/config"
+	database "task_manager/data"
+	"task_manager/router"
+	"task_manager/totp"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// idp and api serve the current test.
+var (
+	idp *provider
+	api *httptest.Server
+)
+
+// start runs the mock provider and the API in-process against a throwaway
+// database on TEST_MONGO_URL, dropped when the test ends.
+func start(t *testing.T) {
+	t.Helper()
+	mongoURL := os.Getenv("TEST_MONGO_URL")
+	if mongoURL == "" {
+		t.Skip("TEST_MONGO_URL is not set")
+	}
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp = &provider{clientID: "task-manager", key: key, codes: make(map[string]authorization)}
+	issuer := httptest.NewServer(idp.handler())
+	t.Cleanup(issuer.Close)
+	idp.issuer = issuer.URL
+
+	cfg := config.Default()
+	cfg.Database.URL = mongoURL
+	cfg.Database.Name = "task_manager_mockidp_" + strings.ToLower(rand.Text()[:8])
+	cfg.Auth.JWTSecret = "mockidp-test-secret-0123456789abcdef"
+	cfg.RateLimit.Enabled = false
+	cfg.OIDC.Issuer = issuer.URL
+	cfg.OIDC.ClientID = "task-manager"
+	cfg.OIDC.AdminGroups = []string{"task-admins"}
+	cfg.OIDC.AutoProvision = true
+
+	ctx := context.Background()
+	if err := database.Connect(ctx, cfg.Database); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURL))
+		if err == nil {
+			client.Database(cfg.Database.Name).Drop(ctx)
+			client.Disconnect(ctx)
+		}
+		database.Disconnect(ctx)
+	})
+
+	gin.SetMode(gin.TestMode)
+	api = httptest.NewUnstartedServer(nil)
+	cfg.OIDC.RedirectURL = "http://" + api.Listener.Addr().String() + "/auth/oidc/callback"
+	api.Config.Handler = router.SetupRouter(cfg)
+	api.Start()
+	t.Cleanup(api.Close)
+}
+
+// signInAs makes the provider assert the given identity.
+func signInAs(subject, username, email string, groups ...string) {
+	idp.subject, idp.username, idp.email, idp.groups = subject, username, email, groups
+}
+
+// browser follows redirects and keeps cookies, like the user's browser.
+func browser(t *testing.T) *http.Client {
+	t.Helper()
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &http.Client{Jar: jar, Timeout: 10 * time.Second}
+}
+
+func call(t *testing.T, client *http.Client, method, url, token string, body any, out any) int {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: decode response: %v", method, url, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+type loginResponse struct {
+	Token             string `json:"token"`
+	Username          string `json:"username"`
+	Role              string `json:"role"`
+	TwoFactorRequired bool   `json:"twoFactorRequired"`
+	ChallengeToken    string `json:"challengeToken"`
+	Code              string `json:"code"`
+}
+
+// ssoLogin runs the whole browser flow: the API redirects to the provider,
+// which redirects back to the callback with a code.
+func ssoLogin(t *testing.T) (int, loginResponse) {
+	t.Helper()
+	var resp loginResponse
+	status := call(t, browser(t), http.MethodGet, api.URL+"/auth/oidc/login", "", nil, &resp)
+	return status, resp
+}
+
+func TestSSOProvisionsAccountsWithMappedRoles(t *testing.T) {
+	start(t)
+
+	signInAs("sub-admin", "ada", "ada@example.com", "task-admins")
+	status, resp := ssoLogin(t)
+	if status != http.StatusOK || resp.Token == "" {
+		t.Fatalf("first login = %d %+v, want a token", status, resp)
+	}
+	if resp.Username != "ada" || resp.Role != "admin" {
+		t.Errorf("provisioned %s as %s, want ada as admin", resp.Username, resp.Role)
+	}
+
+	signInAs("sub-member", "bob", "bob@example.com")
+	status, resp = ssoLogin(t)
+	if status != http.StatusOK || resp.Role != "user" {
+		t.Fatalf("login without admin group = %d %+v, want role user", status, resp)
+	}
+
+	// The same subject signs in to the same account, whatever the email.
+	signInAs("sub-member", "bob", "robert@example.com")
+	status, again := ssoLogin(t)
+	if status != http.StatusOK || again.Username != "bob" {
+		t.Fatalf("second login = %d %+v, want the bob account", status, again)
+	}
+
+	// A password-less account sets its first password without one, as the
+	// session has just signed in.
+	body := map[string]string{"newPassword": "Correct-Horse-Battery-9"}
+	if status := call(t, http.DefaultClient, http.MethodPost, api.URL+"/me/password", again.Token, body, nil); status != http.StatusOK {
+		t.Errorf("setting the first password = %d, want 200", status)
+	}
+}
+
+func TestSSORejectsTamperedFlows(t *testing.T) {
+	start(t)
+	signInAs("sub-tamper", "mallory", "mallory@example.com")
+
+	client := browser(t)
+	client.CheckRedirect = func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }
+
+	// authorize starts a flow and returns the provider URL it redirects to.
+	authorize := func() *url.URL {
+		resp, err := client.Get(api.URL + "/auth/oidc/login")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		location, err := resp.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return location
+	}
+
+	// callback sends the provider's redirect on to the API.
+	callback := func(providerURL *url.URL, edit func(url.Values)) loginResponse {
+		resp, err := client.Get(providerURL.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		location, err := resp.Location()
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := location.Query()
+		if edit != nil {
+			edit(query)
+		}
+		location.RawQuery = query.Encode()
+
+		var body loginResponse
+		call(t, client, http.MethodGet, location.String(), "", nil, &body)
+		return body
+	}
+
+	tests := []struct {
+		name     string
+		provider func(url.Values)
+		callback func(url.Values)
+		want     string
+	}{
+		{"state", nil, func(q url.Values) { q.Set("state", "forged") }, "invalid_sso_state"},
+		{"nonce", func(q url.Values) { q.Set("nonce", "replayed") }, nil, "sso_not_verified"},
+		{"pkce", func(q url.Values) { q.Set("code_challenge", "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM") }, nil, "sso_not_verified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providerURL := authorize()
+			if tt.provider != nil {
+				query := providerURL.Query()
+				tt.provider(query)
+				providerURL.RawQuery = query.Encode()
+			}
+			if got := callback(providerURL, tt.callback); got.Code != tt.want {
+				t.Errorf("code = %q, want %q", got.Code, tt.want)
+			}
+		})
+	}
+
+	if got := callback(authorize(), nil); got.Token == "" {
+		t.Errorf("untampered flow = %+v, want a token", got)
+	}
+}
+
+func TestSSOLinksProtectedAccountsOnlyFromASession(t *testing.T) {
+	start(t)
+	ctx := context.Background()
+
+	user, err := database.CreateUser(ctx, "carol", "Correct-Horse-Battery-9", "carol@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.MarkEmailVerified(ctx, user.ID, "carol@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := database.PromoteUser(ctx, "carol"); err != nil {
+		t.Fatal(err)
+	}
+
+	signInAs("sub-carol", "carol-sso", "carol@example.com")
+	if status, resp := ssoLogin(t); status != http.StatusForbidden || resp.Code != "sso_link_required" {
+		t.Fatalf("login matching an admin's email = %d %+v, want 403 sso_link_required", status, resp)
+	}
+
+	var login loginResponse
+	credentials := map[string]string{"username": "carol", "password": "Correct-Horse-Battery-9"}
+	if status := call(t, http.DefaultClient, http.MethodPost, api.URL+"/auth/login", "", credentials, &login); status != http.StatusOK {
+		t.Fatalf("password login = %d", status)
+	}
+
+	var link struct {
+		AuthorizationURL string `json:"authorizationUrl"`
+	}
+	client := browser(t)
+	if status := call(t, client, http.MethodPost, api.URL+"/me/sso/link", login.Token, nil, &link); status != http.StatusOK {
+		t.Fatalf("starting the link = %d", status)
+	}
+	if status := call(t, client, http.MethodGet, link.AuthorizationURL, "", nil, nil); status != http.StatusOK {
+		t.Fatalf("completing the link = %d", status)
+	}
+
+	status, resp := ssoLogin(t)
+	if status != http.StatusOK || resp.Username != "carol" {
+		t.Fatalf("login after linking = %d %+v, want the carol account", status, resp)
+	}
+
+	// With 2FA on, SSO gets the same challenge as a password login.
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := database.SetPendingTOTPSecret(ctx, user.ID, secret); err != nil {
+		t.Fatal(err)
+	}
+	recoveryCodes, err := database.EnableTOTP(ctx, user.ID, secret, totp.Step(time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	status, resp = ssoLogin(t)
+	if status != http.StatusOK || resp.Token != "" || !resp.TwoFactorRequired {
+		t.Fatalf("login with 2FA = %d %+v, want a challenge and no token", status, resp)
+	}
+
+	var second loginResponse
+	body := map[string]string{"challengeToken": resp.ChallengeToken, "recoveryCode": recoveryCodes[0]}
+	if status := call(t, http.DefaultClient, http.MethodPost, api.URL+"/auth/login/2fa", "", body, &second); status != http.StatusOK || second.Token == "" {
+		t.Fatalf("completing the challenge = %d %+v", status, second)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"task_manager/client"
+)
+
+var stdin = bufio.NewReader(os.Stdin)
+
+func login(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("login", "login [-username name] [-password-stdin]")
+	username := fs.String("username", "", "username; prompted for if empty")
+	passwordStdin := fs.Bool("password-stdin", false, "read the password from standard input")
+	if _, err := parse(fs, opts, args, 0); err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	if *username == "" {
+		if *username, err = prompt("Username: ", false); err != nil {
+			return err
+		}
+	}
+	var password string
+	if *passwordStdin {
+		password, err = readLine()
+	} else {
+		password, err = prompt("Password: ", true)
+	}
+	if err != nil {
+		return err
+	}
+
+	server := st.serverURL(opts.server)
+	c, err := client.New(client.Config{
+		BaseURL: server,
+		TwoFactorCode: func(ctx context.Context) (string, error) {
+			return prompt("Two-factor code: ", false)
+		},
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := c.Login(ctx, *username, password)
+	if err != nil {
+		return err
+	}
+
+	st.Server, st.Token = server, resp.Token
+	if err := st.save(); err != nil {
+		return err
+	}
+	return message(opts.output, fmt.Sprintf("logged in to %s as %s", server, resp.Username))
+}
+
+// logout only forgets the token; the session ends when it expires or is
+// revoked with DELETE /me/sessions/:sessionId.
+func logout(args []string) error {
+	fs, opts := newFlagSet("logout", "logout")
+	if _, err := parse(fs, opts, args, 0); err != nil {
+		return err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	st.Token = ""
+	if err := st.save(); err != nil {
+		return err
+	}
+	return message(opts.output, "logged out")
+}
+
+func workspaces(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: taskctl workspaces list|use")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs, opts := newFlagSet("workspaces list", "workspaces list")
+		if _, err := parse(fs, opts, args[1:], 0); err != nil {
+			return err
+		}
+		st, err := loadState()
+		if err != nil {
+			return err
+		}
+		c, err := newClient(opts, st)
+		if err != nil {
+			return err
+		}
+		list, err := c.ListWorkspaces(ctx)
+		if err != nil {
+			return err
+		}
+		return render(opts.output, list, workspaceTable(list))
+
+	case "use":
+		fs, opts := newFlagSet("workspaces use", "workspaces use <id>")
+		rest, err := parse(fs, opts, args[1:], 1)
+		if err != nil {
+			return err
+		}
+		id, err := strconv.Atoi(rest[0])
+		if err != nil {
+			return fmt.Errorf("invalid workspace ID %q", rest[0])
+		}
+		st, err := loadState()
+		if err != nil {
+			return err
+		}
+		c, err := newClient(opts, st)
+		if err != nil {
+			return err
+		}
+		workspace, err := c.GetWorkspace(ctx, id)
+		if err != nil {
+			return err
+		}
+		st.Workspace = workspace.ID
+		if err := st.save(); err != nil {
+			return err
+		}
+		return message(opts.output, fmt.Sprintf("using workspace %d (%s)", workspace.ID, workspace.Name))
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown workspaces command %q\n", args[0])
+	return errUsage
+}
+
+func promote(ctx context.Context, args []string) error {
+	fs, opts := newFlagSet("promote", "promote <username>")
+	rest, err := parse(fs, opts, args, 1)
+	if err != nil {
+		return err
+	}
+	username := rest[0]
+
+	st, err := loadState()
+	if err != nil {
+		return err
+	}
+	c, err := newClient(opts, st)
+	if err != nil {
+		return err
+	}
+	if err := c.Promote(ctx, username); err != nil {
+		return err
+	}
+	return message(opts.output, username+" is now an admin")
+}
+
+// prompt asks on standard error and reads a line. With secret set it turns
+// off the terminal echo where stty is available.
+func prompt(label string, secret bool) (string, error) {
+	fmt.Fprint(os.Stderr, label)
+	if secret && setEcho(false) == nil {
+		defer func() {
+			setEcho(true)
+			fmt.Fprintln(os.Stderr)
+		}()
+	}
+	return readLine()
+}
+
+func readLine() (string, error) {
+	line, err := stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return "", fmt.Errorf("read input: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func setEcho(on bool) error {
+	mode := "-echo"
+	if on {
+		mode = "echo"
+	}
+	cmd := exec.Command("stty", mode)
+	cmd.Stdin = os.Stdin
+	return cmd.Run()
+}
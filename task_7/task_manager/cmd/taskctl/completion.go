@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+const bashCompletion = `# taskctl bash completion; load with: source <(taskctl completion bash)
+_taskctl() {
+    local cur prev words cword
+    _init_completion 2>/dev/null || {
+        cur="${COMP_WORDS[COMP_CWORD]}"
+        prev="${COMP_WORDS[COMP_CWORD-1]}"
+        words=("${COMP_WORDS[@]}")
+        cword=$COMP_CWORD
+    }
+
+    case "$prev" in
+        -o|--o|-output|--output) COMPREPLY=($(compgen -W "table json yaml" -- "$cur")); return ;;
+        -status|--status) COMPREPLY=($(compgen -W "open done" -- "$cur")); return ;;
+        -priority|--priority) COMPREPLY=($(compgen -W "low medium high" -- "$cur")); return ;;
+        -server|--server|-workspace|--workspace|-username|--username|-title|--title|-description|--description|-due|--due|-search|--search|-due-before|--due-before) return ;;
+    esac
+
+    local common="-server -o -output"
+    local task_fields="-title -description -due -priority -done"
+    case "${words[1]}" in
+        login) COMPREPLY=($(compgen -W "$common -username -password-stdin" -- "$cur")) ;;
+        logout|promote) COMPREPLY=($(compgen -W "$common" -- "$cur")) ;;
+        completion) [ "$cword" -eq 2 ] && COMPREPLY=($(compgen -W "bash zsh fish" -- "$cur")) ;;
+        workspaces)
+            if [ "$cword" -eq 2 ]; then
+                COMPREPLY=($(compgen -W "list use" -- "$cur"))
+            else
+                COMPREPLY=($(compgen -W "$common" -- "$cur"))
+            fi ;;
+        tasks)
+            if [ "$cword" -eq 2 ]; then
+                COMPREPLY=($(compgen -W "list get create update delete" -- "$cur"))
+                return
+            fi
+            case "${words[2]}" in
+                list) COMPREPLY=($(compgen -W "$common -workspace -status -priority -search -due-before" -- "$cur")) ;;
+                create|update) COMPREPLY=($(compgen -W "$common -workspace $task_fields" -- "$cur")) ;;
+                *) COMPREPLY=($(compgen -W "$common -workspace" -- "$cur")) ;;
+            esac ;;
+        *) [ "$cword" -eq 1 ] && COMPREPLY=($(compgen -W "login logout workspaces tasks promote completion help" -- "$cur")) ;;
+    esac
+}
+complete -F _taskctl taskctl
+`
+
+const zshCompletion = `#compdef taskctl
+# taskctl zsh completion; load with: source <(taskctl completion zsh)
+autoload -U +X bashcompinit && bashcompinit
+` + bashCompletion
+
+const fishCompletion = `# taskctl fish completion; load with: taskctl completion fish | source
+set -l commands login logout workspaces tasks promote completion help
+complete -c taskctl -f
+complete -c taskctl -n "not __fish_seen_subcommand_from $commands" -a "$commands"
+complete -c taskctl -n "__fish_seen_subcommand_from workspaces; and not __fish_seen_subcommand_from list use" -a "list use"
+complete -c taskctl -n "__fish_seen_subcommand_from tasks; and not __fish_seen_subcommand_from list get create update delete" -a "list get create update delete"
+complete -c taskctl -n "__fish_seen_subcommand_from completion" -a "bash zsh fish"
+complete -c taskctl -o server -r -d "API address"
+complete -c taskctl -o o -o output -x -a "table json yaml" -d "output format"
+complete -c taskctl -n "__fish_seen_subcommand_from login" -o username -r -d "username"
+complete -c taskctl -n "__fish_seen_subcommand_from login" -o password-stdin -d "read the password from standard input"
+complete -c taskctl -n "__fish_seen_subcommand_from tasks" -o workspace -r -d "workspace ID"
+complete -c taskctl -n "__fish_seen_subcommand_from list" -o status -x -a "open done"
+complete -c taskctl -n "__fish_seen_subcommand_from tasks" -o priority -x -a "low medium high"
+complete -c taskctl -n "__fish_seen_subcommand_from list" -o search -r -d "text in title or description"
+complete -c taskctl -n "__fish_seen_subcommand_from list" -o due-before -r -d "date"
+complete -c taskctl -n "__fish_seen_subcommand_from create update" -o title -r
+complete -c taskctl -n "__fish_seen_subcommand_from create update" -o description -r
+complete -c taskctl -n "__fish_seen_subcommand_from create update" -o due -r -d "YYYY-MM-DD or RFC 3339"
+complete -c taskctl -n "__fish_seen_subcommand_from create update" -o done -d "mark done"
+`
+
+func completion(args []string) error {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "usage: taskctl completion bash|zsh|fish")
+		return errUsage
+	}
+
+	scripts := map[string]string{"bash": bashCompletion, "zsh": zshCompletion, "fish": fishCompletion}
+	script, ok := scripts[args[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unsupported shell %q, use bash, zsh or fish\n", args[0])
+		return errUsage
+	}
+	fmt.Print(script)
+	return nil
+}
@@ -0,0 +1,149 @@
+// Command taskctl is a command-line client for the task manager API.
+//
+//	taskctl login -username alice
+//	taskctl workspaces use 1
+//	taskctl tasks list -status open -o json
+//
+// The login token, server address and default workspace are kept in
+// taskctl/config.json under the user config directory. Run "taskctl help"
+// for every command.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"task_manager/client"
+)
+
+const usage = `usage: taskctl <command> [flags] [args]
+
+Commands:
+  login                       sign in and store the token
+  logout                      forget the stored token
+  workspaces list             list your workspaces
+  workspaces use <id>         set the default workspace for task commands
+  tasks list                  list the tasks of a workspace
+  tasks get <id>              show one task
+  tasks create -title <t>     create a task
+  tasks update <id>           change the given fields of a task
+  tasks delete <id>           delete a task
+  promote <username>          make a user an admin
+  completion bash|zsh|fish    print a shell completion script
+
+Every command accepts -server (default $TASKCTL_SERVER, the stored server,
+or http://localhost:8080) and -o table|json|yaml. Run
+"taskctl <command> -h" for a command's flags.`
+
+// errUsage is returned for a malformed command line, which exits with
+// status 2 after the usage has been printed.
+var errUsage = errors.New("usage")
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	os.Exit(run(ctx, os.Args[1:]))
+}
+
+func run(ctx context.Context, args []string) int {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	if args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		fmt.Println(usage)
+		return 0
+	}
+
+	var err error
+	switch args[0] {
+	case "login":
+		err = login(ctx, args[1:])
+	case "logout":
+		err = logout(args[1:])
+	case "workspaces":
+		err = workspaces(ctx, args[1:])
+	case "tasks":
+		err = tasks(ctx, args[1:])
+	case "promote":
+		err = promote(ctx, args[1:])
+	case "completion":
+		err = completion(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s\n", args[0], usage)
+		return 2
+	}
+
+	var apiErr *client.Error
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized && apiErr.Code != "invalid_credentials":
+		fmt.Fprintf(os.Stderr, "error: %v\nrun \"taskctl login\" to sign in again\n", err)
+	default:
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+	return 1
+}
+
+// options are the flags every command accepts.
+type options struct {
+	server string
+	output string
+}
+
+func newFlagSet(name, usage string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: taskctl %s\n\nFlags:\n", usage)
+		fs.PrintDefaults()
+	}
+	opts := &options{}
+	fs.StringVar(&opts.server, "server", "", "API address")
+	fs.StringVar(&opts.output, "o", "table", "output format: table, json or yaml")
+	fs.StringVar(&opts.output, "output", "table", "output format: table, json or yaml")
+	return fs, opts
+}
+
+// parse parses args, which may mix flags and positional arguments, and
+// returns the positional ones after checking how many there are.
+func parse(fs *flag.FlagSet, opts *options, args []string, positional int) ([]string, error) {
+	var rest []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		rest, args = append(rest, args[0]), args[1:]
+	}
+
+	if len(rest) != positional {
+		fs.Usage()
+		return nil, errUsage
+	}
+	if _, ok := formats[opts.output]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown output format %q, use table, json or yaml\n", opts.output)
+		return nil, errUsage
+	}
+	return rest, nil
+}
+
+// newClient returns a client for the stored token and the chosen server.
+func newClient(opts *options, st *state) (*client.Client, error) {
+	return client.New(client.Config{BaseURL: st.serverURL(opts.server), Token: st.Token})
+}
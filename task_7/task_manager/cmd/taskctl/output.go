@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	database "task_manager/data"
+
+	"github.com/goccy/go-yaml"
+)
+
+var formats = map[string]bool{"table": true, "json": true, "yaml": true}
+
+// render writes value as JSON or YAML, or calls table to write it as
+// columns.
+func render(format string, value any, table func(w io.Writer)) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case "yaml":
+		// Going through JSON keeps the field names and order of the API.
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		data, err = yaml.JSONToYAML(data)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func taskTable(tasks []database.TaskModel) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tTITLE\tPRIORITY\tDUE\tDONE")
+		for _, task := range tasks {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
+				task.ID, task.Title, orDash(task.Priority), orDash(task.DueDate), yesNo(task.Status))
+		}
+	}
+}
+
+func workspaceTable(workspaces []database.WorkspaceModel) func(w io.Writer) {
+	return func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tNAME\tMEMBERS")
+		for _, workspace := range workspaces {
+			fmt.Fprintf(w, "%d\t%s\t%s\n", workspace.ID, workspace.Name, strconv.Itoa(len(workspace.Members)))
+		}
+	}
+}
+
+// message renders a confirmation, as text or as {"message": ...}.
+func message(format, text string) error {
+	return render(format, map[string]string{"message": text}, func(w io.Writer) {
+		fmt.Fprintln(w, text)
+	})
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "yes"
+	}
+	return "no"
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultServer = "http://localhost:8080"
+
+// state is what taskctl remembers between runs. The file holds a login
+// token, so it is only readable by the user.
+type state struct {
+	Server    string `json:"server,omitempty"`
+	Token     string `json:"token,omitempty"`
+	Workspace int    `json:"workspace,omitempty"`
+}
+
+func statePath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "taskctl", "config.json"), nil
+}
+
+// loadState returns the stored state, or an empty one before the first
+// login.
+func loadState() (*state, error) {
+	path, err := statePath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &state{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	st := &state{}
+	if err := json.Unmarshal(data, st); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return st, nil
+}
+
+func (st *state) save() error {
+	path, err := statePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(st, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o600)
+}
+
+// serverURL picks the server from the -server flag, $TASKCTL_SERVER, the
+// stored server or the default, in that order.
+func (st *state) serverURL(flagValue string) string {
+	for _, server := range []string{flagValue, os.Getenv("TASKCTL_SERVER"), st.Server} {
+		if server != "" {
+			return server
+		}
+	}
+	return defaultServer
+}
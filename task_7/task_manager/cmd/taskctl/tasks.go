@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"task_manager/client"
+	database "task_manager/data"
+)
+
+func tasks(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: taskctl tasks list|get|create|update|delete")
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		return listTasks(ctx, args[1:])
+	case "get":
+		return getTask(ctx, args[1:])
+	case "create":
+		return createTask(ctx, args[1:])
+	case "update":
+		return updateTask(ctx, args[1:])
+	case "delete":
+		return deleteTask(ctx, args[1:])
+	}
+	fmt.Fprintf(os.Stderr, "unknown tasks command %q\n", args[0])
+	return errUsage
+}
+
+// taskCommand holds what every task command needs once its flags are
+// parsed.
+type taskCommand struct {
+	client    *client.Client
+	workspace int
+	id        int
+}
+
+func newTaskFlagSet(name, usage string) (*flag.FlagSet, *options, *int) {
+	fs, opts := newFlagSet(name, usage)
+	workspace := fs.Int("workspace", 0, "workspace ID (default $TASKCTL_WORKSPACE or the one set with \"workspaces use\")")
+	return fs, opts, workspace
+}
+
+// setup parses args, which must hold a task ID if withID is set, and
+// resolves the workspace.
+func setup(fs *flag.FlagSet, opts *options, workspace *int, args []string, withID bool) (*taskCommand, error) {
+	positional := 0
+	if withID {
+		positional = 1
+	}
+	rest, err := parse(fs, opts, args, positional)
+	if err != nil {
+		return nil, err
+	}
+
+	st, err := loadState()
+	if err != nil {
+		return nil, err
+	}
+	cmd := &taskCommand{workspace: *workspace}
+	if cmd.workspace == 0 {
+		if env := os.Getenv("TASKCTL_WORKSPACE"); env != "" {
+			if cmd.workspace, err = strconv.Atoi(env); err != nil {
+				return nil, fmt.Errorf("invalid TASKCTL_WORKSPACE %q", env)
+			}
+		} else {
+			cmd.workspace = st.Workspace
+		}
+	}
+	if cmd.workspace == 0 {
+		return nil, errors.New(`no workspace; pass -workspace or run "taskctl workspaces use <id>"`)
+	}
+
+	if withID {
+		if cmd.id, err = strconv.Atoi(rest[0]); err != nil {
+			return nil, fmt.Errorf("invalid task ID %q", rest[0])
+		}
+	}
+
+	cmd.client, err = newClient(opts, st)
+	return cmd, err
+}
+
+// taskFilter selects tasks on the client side; the API returns all tasks of
+// a workspace.
+type taskFilter struct {
+	status    string
+	priority  string
+	search    string
+	dueBefore string
+}
+
+func (f taskFilter) validate() error {
+	switch f.status {
+	case "", "open", "done":
+	default:
+		return fmt.Errorf("invalid -status %q, use open or done", f.status)
+	}
+	switch f.priority {
+	case "", "low", "medium", "high":
+	default:
+		return fmt.Errorf("invalid -priority %q, use low, medium or high", f.priority)
+	}
+	if f.dueBefore != "" {
+		if _, ok := parseDue(f.dueBefore); !ok {
+			return fmt.Errorf("invalid -due-before %q, use YYYY-MM-DD or RFC 3339", f.dueBefore)
+		}
+	}
+	return nil
+}
+
+func (f taskFilter) match(task database.TaskModel) bool {
+	if f.status != "" && task.Status != (f.status == "done") {
+		return false
+	}
+	if f.priority != "" && task.Priority != f.priority {
+		return false
+	}
+	if f.search != "" {
+		search := strings.ToLower(f.search)
+		if !strings.Contains(strings.ToLower(task.Title), search) && !strings.Contains(strings.ToLower(task.Description), search) {
+			return false
+		}
+	}
+	if f.dueBefore != "" {
+		limit, _ := parseDue(f.dueBefore)
+		due, ok := parseDue(task.DueDate)
+		if !ok || !due.Before(limit) {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDue reads a due date in either form the API accepts.
+func parseDue(value string) (time.Time, bool) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, err == nil
+}
+
+func listTasks(ctx context.Context, args []string) error {
+	fs, opts, workspace := newTaskFlagSet("tasks list", "tasks list [-status open|done] [-priority p] [-search text] [-due-before date]")
+	var filter taskFilter
+	fs.StringVar(&filter.status, "status", "", "only open or done tasks")
+	fs.StringVar(&filter.priority, "priority", "", "only tasks with this priority: low, medium or high")
+	fs.StringVar(&filter.search, "search", "", "only tasks whose title or description contains this text")
+	fs.StringVar(&filter.dueBefore, "due-before", "", "only tasks due before this date")
+	cmd, err := setup(fs, opts, workspace, args, false)
+	if err != nil {
+		return err
+	}
+	if err := filter.validate(); err != nil {
+		return err
+	}
+
+	all, err := cmd.client.ListTasks(ctx, cmd.workspace)
+	if err != nil {
+		return err
+	}
+	matched := []database.TaskModel{}
+	for _, task := range all {
+		if filter.match(task) {
+			matched = append(matched, task)
+		}
+	}
+	return render(opts.output, matched, taskTable(matched))
+}
+
+func getTask(ctx context.Context, args []string) error {
+	fs, opts, workspace := newTaskFlagSet("tasks get", "tasks get <id>")
+	cmd, err := setup(fs, opts, workspace, args, true)
+	if err != nil {
+		return err
+	}
+
+	task, err := cmd.client.GetTask(ctx, cmd.workspace, cmd.id)
+	if err != nil {
+		return err
+	}
+	return render(opts.output, task, taskTable([]database.TaskModel{task}))
+}
+
+// taskFields registers the flags that set task fields.
+func taskFields(fs *flag.FlagSet, task *database.TaskModel) {
+	fs.StringVar(&task.Title, "title", "", "title")
+	fs.StringVar(&task.Description, "description", "", "description")
+	fs.StringVar(&task.DueDate, "due", "", "due date, YYYY-MM-DD or RFC 3339")
+	fs.StringVar(&task.Priority, "priority", "", "low, medium or high")
+	fs.BoolVar(&task.Status, "done", false, "mark the task done; -done=false reopens it")
+}
+
+func createTask(ctx context.Context, args []string) error {
+	fs, opts, workspace := newTaskFlagSet("tasks create", "tasks create -title <title> [-description d] [-due date] [-priority p] [-done]")
+	var task database.TaskModel
+	taskFields(fs, &task)
+	cmd, err := setup(fs, opts, workspace, args, false)
+	if err != nil {
+		return err
+	}
+
+	created, err := cmd.client.CreateTask(ctx, cmd.workspace, task)
+	if err != nil {
+		return err
+	}
+	return render(opts.output, created, taskTable([]database.TaskModel{created}))
+}
+
+// updateTask changes only the fields whose flags are given. The API replaces
+// the whole task, so the current one is fetched first.
+func updateTask(ctx context.Context, args []string) error {
+	fs, opts, workspace := newTaskFlagSet("tasks update", "tasks update [-title t] [-description d] [-due date] [-priority p] [-done] <id>")
+	var changes database.TaskModel
+	taskFields(fs, &changes)
+	cmd, err := setup(fs, opts, workspace, args, true)
+	if err != nil {
+		return err
+	}
+
+	task, err := cmd.client.GetTask(ctx, cmd.workspace, cmd.id)
+	if err != nil {
+		return err
+	}
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "title":
+			task.Title = changes.Title
+		case "description":
+			task.Description = changes.Description
+		case "due":
+			task.DueDate = changes.DueDate
+		case "priority":
+			task.Priority = changes.Priority
+		case "done":
+			task.Status = changes.Status
+		}
+	})
+
+	updated, err := cmd.client.UpdateTask(ctx, cmd.workspace, cmd.id, task)
+	if err != nil {
+		return err
+	}
+	return render(opts.output, updated, taskTable([]database.TaskModel{updated}))
+}
+
+func deleteTask(ctx context.Context, args []string) error {
+	fs, opts, workspace := newTaskFlagSet("tasks delete", "tasks delete <id>")
+	cmd, err := setup(fs, opts, workspace, args, true)
+	if err != nil {
+		return err
+	}
+
+	if err := cmd.client.DeleteTask(ctx, cmd.workspace, cmd.id); err != nil {
+		return err
+	}
+	return message(opts.output, fmt.Sprintf("deleted task %d", cmd.id))
+}
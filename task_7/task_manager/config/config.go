@@ -0,0 +1,580 @@
+// Package config holds every setting of the server. Settings are read from,
+// in increasing order of precedence: built-in defaults, a YAML or TOML file,
+// environment variables and command-line flags.
+//
+// Each field of Config carries its key in the config tag. The key is the
+// setting's path in the file and, with underscores turned into dashes, the
+// name of its flag (-server.read-timeout). The env tag names the
+// environment variable, if there is one. Fields tagged secret are masked by
+// Print.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+type Config struct {
+	Server    Server    `config:"server"`
+	Database  Database  `config:"database"`
+	Auth      Auth      `config:"auth"`
+	Login     Login     `config:"login"`
+	RateLimit RateLimit `config:"rate_limit"`
+	Password  Password  `config:"password"`
+	Tasks     Tasks     `config:"tasks"`
+	Mail      Mail      `config:"mail"`
+	OIDC      OIDC      `config:"oidc"`
+	Log       Log       `config:"log"`
+	Metrics   Metrics   `config:"metrics"`
+	Tracing   Tracing   `config:"tracing"`
+
+	// sources records where each setting's value came from, for Print.
+	sources map[string]string
+}
+
+type Server struct {
+	Addr              string        `config:"addr" env:"HTTP_ADDR"`
+	ReadHeaderTimeout time.Duration `config:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `config:"read_timeout" env:"HTTP_READ_TIMEOUT"`
+	WriteTimeout      time.Duration `config:"write_timeout" env:"HTTP_WRITE_TIMEOUT"`
+	IdleTimeout       time.Duration `config:"idle_timeout" env:"HTTP_IDLE_TIMEOUT"`
+	// ShutdownTimeout is how long in-flight requests get to finish after a
+	// SIGINT or SIGTERM.
+	ShutdownTimeout time.Duration `config:"shutdown_timeout" env:"HTTP_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies may set X-Forwarded-For. Login throttling is keyed on
+	// the client IP, so only list proxies you control.
+	TrustedProxies []string `config:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+// Database configures the MongoDB client. Pool sizes and timeouts left at
+// zero keep the value from the URL, or the driver's default.
+type Database struct {
+	URL  string `config:"url" env:"MONGO_URL" secret:"url"`
+	Name string `config:"name" env:"MONGO_DATABASE"`
+
+	MaxPoolSize            int           `config:"max_pool_size" env:"MONGO_MAX_POOL_SIZE"`
+	MinPoolSize            int           `config:"min_pool_size" env:"MONGO_MIN_POOL_SIZE"`
+	MaxConnIdleTime        time.Duration `config:"max_conn_idle_time" env:"MONGO_MAX_CONN_IDLE_TIME"`
+	ConnectTimeout         time.Duration `config:"connect_timeout" env:"MONGO_CONNECT_TIMEOUT"`
+	ServerSelectionTimeout time.Duration `config:"server_selection_timeout" env:"MONGO_SERVER_SELECTION_TIMEOUT"`
+	// StartupTimeout is how long to keep retrying when MongoDB is not
+	// reachable at startup.
+	StartupTimeout time.Duration `config:"startup_timeout" env:"MONGO_STARTUP_TIMEOUT"`
+
+	// Username, if set, overrides the credentials in the URL.
+	Username      string `config:"username" env:"MONGO_USERNAME"`
+	Password      string `config:"password" env:"MONGO_PASSWORD" secret:"true"`
+	AuthSource    string `config:"auth_source" env:"MONGO_AUTH_SOURCE"`
+	AuthMechanism string `config:"auth_mechanism" env:"MONGO_AUTH_MECHANISM"`
+
+	TLS bool `config:"tls" env:"MONGO_TLS"`
+	// TLSCAFile verifies the server against these CAs instead of the
+	// system pool.
+	TLSCAFile string `config:"tls_ca_file" env:"MONGO_TLS_CA_FILE"`
+	// TLSCertKeyFile holds the client certificate and its key in PEM, for
+	// X.509 authentication.
+	TLSCertKeyFile string `config:"tls_cert_key_file" env:"MONGO_TLS_CERT_KEY_FILE"`
+	TLSInsecure    bool   `config:"tls_insecure" env:"MONGO_TLS_INSECURE"`
+
+	Collections Collections `config:"collections"`
+}
+
+// Collections can only be renamed in the file or with flags.
+type Collections struct {
+	Tasks         string `config:"tasks"`
+	Users         string `config:"users"`
+	Counters      string `config:"counters"`
+	Workspaces    string `config:"workspaces"`
+	Invitations   string `config:"invitations"`
+	UserTokens    string `config:"user_tokens"`
+	Settings      string `config:"settings"`
+	LoginAttempts string `config:"login_attempts"`
+	APITokens     string `config:"api_tokens"`
+	Sessions      string `config:"sessions"`
+	AuditLog      string `config:"audit_log"`
+}
+
+type Auth struct {
+	// JWTSecret signs login, invitation and other tokens. Changing it
+	// invalidates every token already issued.
+	JWTSecret string `config:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	// SessionTTL is how long a login lasts.
+	SessionTTL time.Duration `config:"session_ttl" env:"SESSION_TTL"`
+	// OpenRegistration lets anyone call /auth/register; otherwise accounts
+	// are only created from invitations.
+	OpenRegistration bool `config:"open_registration" env:"OPEN_REGISTRATION"`
+}
+
+// Login configures throttling of password and 2FA guessing.
+type Login struct {
+	LockoutThreshold   int           `config:"lockout_threshold" env:"LOGIN_LOCKOUT_THRESHOLD"`
+	IPLockoutThreshold int           `config:"ip_lockout_threshold" env:"LOGIN_IP_LOCKOUT_THRESHOLD"`
+	BackoffBase        time.Duration `config:"backoff_base" env:"LOGIN_BACKOFF_BASE"`
+	LockoutDuration    time.Duration `config:"lockout_duration" env:"LOGIN_LOCKOUT_DURATION"`
+	FailureWindow      time.Duration `config:"failure_window" env:"LOGIN_FAILURE_WINDOW"`
+}
+
+// RateLimit switches the per-client request limits on or off; the limits
+// themselves are set per route group in the router.
+type RateLimit struct {
+	Enabled bool `config:"enabled" env:"RATE_LIMIT_ENABLED"`
+}
+
+type Password struct {
+	MinLength int `config:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	// MinClasses is how many of lowercase, uppercase, digits and symbols
+	// must appear.
+	MinClasses int `config:"min_classes" env:"PASSWORD_MIN_CLASSES"`
+	// BreachedFile is a file of known-breached password hashes sorted by
+	// hash, or a directory of hash-prefix range files.
+	BreachedFile string `config:"breached_file" env:"BREACHED_PASSWORDS_FILE"`
+}
+
+type Tasks struct {
+	// DueDateYearsBack is how many years before today a due date may lie.
+	DueDateYearsBack int `config:"due_date_years_back" env:"TASK_DUE_DATE_YEARS_BACK"`
+}
+
+type Mail struct {
+	// Mailer is console, file or smtp.
+	Mailer       string `config:"mailer" env:"MAILER"`
+	From         string `config:"from" env:"MAIL_FROM"`
+	Dir          string `config:"dir" env:"MAIL_DIR"`
+	SMTPHost     string `config:"smtp_host" env:"SMTP_HOST"`
+	SMTPPort     string `config:"smtp_port" env:"SMTP_PORT"`
+	SMTPUsername string `config:"smtp_username" env:"SMTP_USERNAME"`
+	SMTPPassword string `config:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+}
+
+// OIDC configures single sign-on; it is disabled while Issuer is empty.
+type OIDC struct {
+	Issuer       string   `config:"issuer" env:"OIDC_ISSUER"`
+	ClientID     string   `config:"client_id" env:"OIDC_CLIENT_ID"`
+	ClientSecret string   `config:"client_secret" env:"OIDC_CLIENT_SECRET" secret:"true"`
+	RedirectURL  string   `config:"redirect_url" env:"OIDC_REDIRECT_URL"`
+	Scopes       []string `config:"scopes" env:"OIDC_SCOPES" sep:" "`
+	// GroupsClaim names the ID token claim holding the user's groups.
+	GroupsClaim string `config:"groups_claim" env:"OIDC_GROUPS_CLAIM"`
+	// AdminGroups maps IdP groups to the admin role. When empty, roles are
+	// never changed by SSO logins.
+	AdminGroups []string `config:"admin_groups" env:"OIDC_ADMIN_GROUPS"`
+	// AutoProvision creates a local account on first login when no
+	// existing account can be linked.
+	AutoProvision bool `config:"auto_provision" env:"OIDC_AUTO_PROVISION"`
+}
+
+func (o OIDC) Enabled() bool {
+	return o.Issuer != ""
+}
+
+type Log struct {
+	// Level is debug, info, warn or error.
+	Level string `config:"level" env:"LOG_LEVEL"`
+	// Format is json or text.
+	Format string `config:"format" env:"LOG_FORMAT"`
+}
+
+type Metrics struct {
+	// Token, if set, must be sent as a bearer token to scrape /metrics.
+	Token string `config:"token" env:"METRICS_TOKEN" secret:"true"`
+}
+
+type Tracing struct {
+	// Exporter is none, otlp or stdout. The exporters themselves read the
+	// standard OTEL_* variables.
+	Exporter string `config:"exporter" env:"OTEL_TRACES_EXPORTER"`
+}
+
+// Default returns the settings used when nothing overrides them. It has no
+// JWT secret, which must always be provided.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Addr:              ":8080",
+			ReadHeaderTimeout: 5 * time.Second,
+			ReadTimeout:       15 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       60 * time.Second,
+			ShutdownTimeout:   20 * time.Second,
+		},
+		Database: Database{
+			URL:            "mongodb://localhost:27017",
+			Name:           "task_manager_db",
+			StartupTimeout: time.Minute,
+			Collections: Collections{
+				Tasks:         "tasks",
+				Users:         "users",
+				Counters:      "counters",
+				Workspaces:    "workspaces",
+				Invitations:   "invitations",
+				UserTokens:    "user_tokens",
+				Settings:      "settings",
+				LoginAttempts: "login_attempts",
+				APITokens:     "api_tokens",
+				Sessions:      "sessions",
+				AuditLog:      "audit_log",
+			},
+		},
+		Auth: Auth{
+			SessionTTL:       24 * time.Hour,
+			OpenRegistration: true,
+		},
+		Login: Login{
+			LockoutThreshold:   5,
+			IPLockoutThreshold: 20,
+			BackoffBase:        time.Second,
+			LockoutDuration:    15 * time.Minute,
+			FailureWindow:      15 * time.Minute,
+		},
+		RateLimit: RateLimit{
+			Enabled: true,
+		},
+		Password: Password{
+			MinLength:  8,
+			MinClasses: 2,
+		},
+		Tasks: Tasks{
+			DueDateYearsBack: 10,
+		},
+		Mail: Mail{
+			Mailer:   "console",
+			From:     "task-manager@localhost",
+			Dir:      "mail",
+			SMTPPort: "587",
+		},
+		OIDC: OIDC{
+			Scopes:        []string{"openid", "profile", "email"},
+			GroupsClaim:   "groups",
+			AutoProvision: true,
+		},
+		Log: Log{
+			Level:  "info",
+			Format: "json",
+		},
+		Tracing: Tracing{
+			Exporter: "none",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file named by
+// -config or CONFIG_FILE, the environment and args. It does not validate
+// the result; see Validate. Empty environment variables are ignored.
+func Load(args []string) (*Config, error) {
+	cfg := Default()
+	cfg.sources = map[string]string{}
+	fields := cfg.fields()
+
+	fs := flag.NewFlagSet("task_manager", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file (env CONFIG_FILE)")
+
+	type flagValue struct{ field, value string }
+	var flagValues []flagValue
+	for _, f := range fields {
+		usage := "set " + f.key
+		if f.env != "" {
+			usage += " (env " + f.env + ")"
+		}
+		fs.Func(f.flagName(), usage, func(value string) error {
+			flagValues = append(flagValues, flagValue{f.key, value})
+			return nil
+		})
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if value := os.Getenv(f.env); value != "" {
+			if err := f.set(value); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+			cfg.sources[f.key] = "env " + f.env
+		}
+	}
+
+	byKey := map[string]field{}
+	for _, f := range fields {
+		byKey[f.key] = f
+	}
+	for _, fv := range flagValues {
+		f := byKey[fv.field]
+		if err := f.set(fv.value); err != nil {
+			return nil, fmt.Errorf("-%s: %w", f.flagName(), err)
+		}
+		cfg.sources[f.key] = "flag"
+	}
+
+	return cfg, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var values map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q, use .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	byKey := map[string]field{}
+	for _, f := range c.fields() {
+		byKey[f.key] = f
+	}
+
+	flat := map[string]any{}
+	flatten("", values, flat)
+	for key, value := range flat {
+		f, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if err := f.set(value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+		c.sources[key] = "file"
+	}
+
+	return nil
+}
+
+// flatten turns nested tables into dotted keys.
+func flatten(prefix string, values map[string]any, out map[string]any) {
+	for key, value := range values {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if nested, ok := value.(map[string]any); ok {
+			flatten(key, nested, out)
+			continue
+		}
+		out[key] = value
+	}
+}
+
+// Validate reports every setting that is missing or out of range.
+func (c *Config) Validate() error {
+	var problems []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			problems = append(problems, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Server.Addr != "", "server.addr is required")
+	for key, d := range map[string]time.Duration{
+		"server.read_header_timeout": c.Server.ReadHeaderTimeout,
+		"server.read_timeout":        c.Server.ReadTimeout,
+		"server.write_timeout":       c.Server.WriteTimeout,
+		"server.idle_timeout":        c.Server.IdleTimeout,
+		"server.shutdown_timeout":    c.Server.ShutdownTimeout,
+		"auth.session_ttl":           c.Auth.SessionTTL,
+		"login.backoff_base":         c.Login.BackoffBase,
+		"login.lockout_duration":     c.Login.LockoutDuration,
+		"login.failure_window":       c.Login.FailureWindow,
+	} {
+		check(d > 0, "%s must be positive", key)
+	}
+
+	check(c.Database.URL != "", "database.url is required")
+	check(c.Database.Name != "", "database.name is required")
+	check(c.Database.StartupTimeout > 0, "database.startup_timeout must be positive")
+	check(c.Database.MaxPoolSize >= 0 && c.Database.MinPoolSize >= 0, "database pool sizes must not be negative")
+	check(c.Database.MaxPoolSize == 0 || c.Database.MinPoolSize <= c.Database.MaxPoolSize,
+		"database.min_pool_size must not exceed database.max_pool_size")
+	for key, d := range map[string]time.Duration{
+		"database.max_conn_idle_time":       c.Database.MaxConnIdleTime,
+		"database.connect_timeout":          c.Database.ConnectTimeout,
+		"database.server_selection_timeout": c.Database.ServerSelectionTimeout,
+	} {
+		check(d >= 0, "%s must not be negative", key)
+	}
+	check(c.Database.TLS || (c.Database.TLSCAFile == "" && c.Database.TLSCertKeyFile == "" && !c.Database.TLSInsecure),
+		"database.tls must be enabled to use the other database.tls_* settings")
+	seen := map[string]string{}
+	for _, f := range c.fields() {
+		if !strings.HasPrefix(f.key, "database.collections.") {
+			continue
+		}
+		name := f.value.String()
+		check(name != "", "%s is required", f.key)
+		if other, ok := seen[name]; ok && name != "" {
+			check(false, "%s and %s use the same collection %q", other, f.key, name)
+		}
+		seen[name] = f.key
+	}
+
+	check(len(c.Auth.JWTSecret) >= 32, "auth.jwt_secret (JWT_SECRET) must be at least 32 bytes")
+
+	check(c.Login.LockoutThreshold > 0, "login.lockout_threshold must be positive")
+	check(c.Login.IPLockoutThreshold > 0, "login.ip_lockout_threshold must be positive")
+
+	check(c.Password.MinLength >= 1, "password.min_length must be at least 1")
+	check(c.Password.MinClasses >= 0 && c.Password.MinClasses <= 4, "password.min_classes must be between 0 and 4")
+
+	check(c.Tasks.DueDateYearsBack > 0, "tasks.due_date_years_back must be positive")
+
+	switch c.Mail.Mailer {
+	case "console", "file":
+	case "smtp":
+		check(c.Mail.SMTPHost != "", "mail.smtp_host is required when mail.mailer is smtp")
+	default:
+		check(false, "mail.mailer must be console, file or smtp, not %q", c.Mail.Mailer)
+	}
+
+	if c.OIDC.Enabled() {
+		check(c.OIDC.ClientID != "", "oidc.client_id is required when oidc.issuer is set")
+		check(c.OIDC.RedirectURL != "", "oidc.redirect_url is required when oidc.issuer is set")
+	}
+
+	switch strings.ToLower(c.Log.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		check(false, "log.level must be debug, info, warn or error, not %q", c.Log.Level)
+	}
+	check(c.Log.Format == "json" || c.Log.Format == "text", "log.format must be json or text, not %q", c.Log.Format)
+
+	switch c.Tracing.Exporter {
+	case "none", "otlp", "stdout":
+	default:
+		check(false, "tracing.exporter must be none, otlp or stdout, not %q", c.Tracing.Exporter)
+	}
+
+	return errors.Join(problems...)
+}
+
+// field is one setting, addressed into a Config.
+type field struct {
+	key    string
+	env    string
+	secret string
+	sep    string
+	value  reflect.Value
+}
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(f.key, "_", "-")
+}
+
+var durationType = reflect.TypeFor[time.Duration]()
+
+// fields lists the settings in declaration order.
+func (c *Config) fields() []field {
+	var fields []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := range v.NumField() {
+			sf := v.Type().Field(i)
+			name, ok := sf.Tag.Lookup("config")
+			if !ok {
+				continue
+			}
+			key := prefix + name
+			if sf.Type.Kind() == reflect.Struct {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			sep := sf.Tag.Get("sep")
+			if sep == "" {
+				sep = ","
+			}
+			fields = append(fields, field{
+				key:    key,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret"),
+				sep:    sep,
+				value:  v.Field(i),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return fields
+}
+
+// set parses raw, a string from the environment or a flag or a decoded file
+// value, into the field.
+func (f field) set(raw any) error {
+	if list, ok := raw.([]any); ok {
+		if f.value.Type() != reflect.TypeFor[[]string]() {
+			return errors.New("expected a single value, got a list")
+		}
+		values := make([]string, 0, len(list))
+		for _, item := range list {
+			values = append(values, fmt.Sprint(item))
+		}
+		f.value.Set(reflect.ValueOf(values))
+		return nil
+	}
+
+	value := fmt.Sprint(raw)
+	switch {
+	case f.value.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", value)
+		}
+		f.value.SetInt(int64(d))
+	case f.value.Kind() == reflect.String:
+		f.value.SetString(value)
+	case f.value.Kind() == reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", value)
+		}
+		f.value.SetInt(int64(n))
+	case f.value.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+		f.value.SetBool(b)
+	case f.value.Kind() == reflect.Slice:
+		var values []string
+		if f.sep == " " {
+			values = strings.Fields(value)
+		} else {
+			for _, item := range strings.Split(value, f.sep) {
+				if item = strings.TrimSpace(item); item != "" {
+					values = append(values, item)
+				}
+			}
+		}
+		f.value.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported setting type %s", f.value.Type())
+	}
+	return nil
+}
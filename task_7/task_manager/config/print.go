@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const mask = "********"
+
+// urlPassword matches the password in a connection string's user info.
+var urlPassword = regexp.MustCompile(`(://[^:/@]*:)[^@/]*@`)
+
+// Print writes the effective settings as YAML, which can be used as a config
+// file, with each value's source as a comment. Secrets are masked.
+func (c *Config) Print(w io.Writer) error {
+	var b strings.Builder
+	var section []string
+
+	for _, f := range c.fields() {
+		parts := strings.Split(f.key, ".")
+		path, name := parts[:len(parts)-1], parts[len(parts)-1]
+
+		common := 0
+		for common < len(path) && common < len(section) && path[common] == section[common] {
+			common++
+		}
+		for i := common; i < len(path); i++ {
+			fmt.Fprintf(&b, "%s%s:\n", strings.Repeat("  ", i), path[i])
+		}
+		section = path
+
+		source := c.sources[f.key]
+		if source == "" {
+			source = "default"
+		}
+		fmt.Fprintf(&b, "%s%s: %s # %s\n", strings.Repeat("  ", len(path)), name, f.format(), source)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// format renders the field's value as YAML.
+func (f field) format() string {
+	switch f.secret {
+	case "true":
+		if f.value.String() != "" {
+			return strconv.Quote(mask)
+		}
+	case "url":
+		return strconv.Quote(urlPassword.ReplaceAllString(f.value.String(), "${1}"+mask+"@"))
+	}
+
+	switch {
+	case f.value.Type() == durationType:
+		return strconv.Quote(time.Duration(f.value.Int()).String())
+	case f.value.Kind() == reflect.String:
+		return strconv.Quote(f.value.String())
+	case f.value.Kind() == reflect.Slice:
+		items := make([]string, f.value.Len())
+		for i := range items {
+			items[i] = strconv.Quote(f.value.Index(i).String())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	default:
+		return fmt.Sprint(f.value.Interface())
+	}
+}
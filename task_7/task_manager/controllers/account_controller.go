@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"task_manager/config"
+	database "task_manager/data"
+	"task_manager/mailer"
+	"task_manager/middleware"
+	"task_manager/models"
+	"task_manager/passwordpolicy"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountController struct {
+	mailer         mailer.Mailer
+	passwordPolicy *passwordpolicy.Policy
+	// guard throttles the codes and passwords checked by the 2FA endpoints,
+	// password changes and account deletion with the same counters as logins.
+	guard loginGuard
+}
+
+func NewAccountController(m mailer.Mailer, policy *passwordpolicy.Policy, login config.Login) *AccountController {
+	return &AccountController{mailer: m, passwordPolicy: policy, guard: newLoginGuard(login)}
+}
+
+func (ac *AccountController) GetProfile(c *gin.Context) {
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (ac *AccountController) UpdateProfile(c *gin.Context) {
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	current, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	// Re-sending the current address must not drop its verified status.
+	if req.Email != nil && *req.Email == current.Email {
+		req.Email = nil
+	}
+
+	user, err := database.UpdateProfile(c.Request.Context(), current.ID, req.DisplayName, req.Email, req.Timezone)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if req.Email != nil && user.Email != "" {
+		if err := sendVerificationEmail(c.Request.Context(), ac.mailer, user); err != nil {
+			slog.ErrorContext(c.Request.Context(), "error sending verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+func (ac *AccountController) ResendVerification(c *gin.Context) {
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if user.Email == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeNoEmailAddress))
+		return
+	}
+
+	if user.EmailVerified {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeEmailAlreadyVerified))
+		return
+	}
+
+	if err := sendVerificationEmail(c.Request.Context(), ac.mailer, user); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "verification email sent"})
+}
+
+// ChangePassword signs out every other session by bumping the token version,
+// and returns a new token for the current session so the caller stays
+// signed in. Accounts without a password set their first one here.
+func (ac *AccountController) ChangePassword(c *gin.Context) {
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	attempt, ok := ac.guard.begin(c, user.Username)
+	if !ok {
+		return
+	}
+	defer attempt.release()
+
+	confirmed, err := checkPassword(c, user, req.CurrentPassword)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if !confirmed {
+		attempt.fail()
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+	attempt.succeed()
+
+	if rejectWeakPassword(c, ac.passwordPolicy, "newPassword", user.Username, req.NewPassword) {
+		return
+	}
+
+	user, err = database.ChangePassword(c.Request.Context(), user.ID, req.NewPassword)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	sessionID := c.GetInt("session_id")
+	if _, err := database.RevokeAllSessions(context.WithoutCancel(c.Request.Context()), user.ID, sessionID); err != nil {
+		slog.ErrorContext(c.Request.Context(), "error revoking sessions", "user_id", user.ID, "error", err)
+	}
+
+	session, err := database.GetActiveSession(c.Request.Context(), user.ID, sessionID)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "account.change_password", TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+
+	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role, user.TokenVersion, sessionID, session.ExpiresAt)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.ChangePasswordResponse{
+		Message: "password changed successfully",
+		Token:   token,
+	})
+}
+
+func (ac *AccountController) ExportData(c *gin.Context) {
+	export, err := exportAccount(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="account-export.json"`)
+	c.JSON(http.StatusOK, export)
+}
+
+// DeleteAccount removes the caller's account after confirming the password,
+// or a recent sign-in for accounts without one.
+// The response carries the same export as GET /me/export so nothing is lost
+// if the client did not fetch it beforehand. Owned tasks stay in their
+// workspaces but become unowned.
+func (ac *AccountController) DeleteAccount(c *gin.Context) {
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	attempt, ok := ac.guard.begin(c, user.Username)
+	if !ok {
+		return
+	}
+	defer attempt.release()
+
+	confirmed, err := checkPassword(c, user, req.Password)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if !confirmed {
+		attempt.fail()
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+	attempt.succeed()
+
+	export, err := exportAccount(c.Request.Context(), user.ID)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	// Once removal has started it runs to the end even if the client hangs
+	// up, so the account is not left half deleted.
+	ctx := context.WithoutCancel(c.Request.Context())
+	if !retireAccount(c, ctx, user, export.Workspaces) {
+		return
+	}
+
+	if _, err := database.ReassignTasks(ctx, user.ID, 0); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if err := database.RevokeAllAPITokens(ctx, user.ID); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if err := database.DeleteUser(ctx, user.ID); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "account.delete",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Detail:     "username=" + user.Username,
+	})
+
+	c.JSON(http.StatusOK, models.DeleteAccountResponse{
+		Message: "account deleted successfully",
+		Export:  export,
+	})
+}
+
+func exportAccount(ctx context.Context, userID int) (models.AccountExport, error) {
+	user, err := database.GetUserByID(ctx, userID)
+	if err != nil {
+		return models.AccountExport{}, err
+	}
+
+	tasks, err := database.GetTasksByOwner(ctx, userID)
+	if err != nil {
+		return models.AccountExport{}, err
+	}
+
+	workspaces, err := database.GetWorkspacesForUser(ctx, userID)
+	if err != nil {
+		return models.AccountExport{}, err
+	}
+
+	return models.AccountExport{User: user, Tasks: tasks, Workspaces: workspaces}, nil
+}
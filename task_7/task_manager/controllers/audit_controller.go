@@ -0,0 +1,216 @@
+package controllers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/metrics"
+	"task_manager/models"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAudit appends entry to the audit log, filling in the signed-in
+// actor, workspace and client IP from the request when not set. A failed
+// write is logged rather than failing the request.
+func recordAudit(c *gin.Context, entry database.AuditEntryModel) {
+	if entry.ActorID == 0 && entry.ActorName == "" {
+		entry.ActorID = c.GetInt("user_id")
+		entry.ActorName = c.GetString("username")
+	}
+	if entry.WorkspaceID == 0 {
+		entry.WorkspaceID = c.GetInt("workspace_id")
+	}
+	if entry.Result == "" {
+		entry.Result = database.AuditResultSuccess
+	}
+	entry.IP = c.ClientIP()
+
+	// The action has already happened, so the entry is written even if the
+	// client has gone away.
+	if _, err := database.AppendAudit(context.WithoutCancel(c.Request.Context()), entry); err != nil {
+		slog.ErrorContext(c.Request.Context(), "error writing audit entry", "action", entry.Action, "error", err)
+	}
+}
+
+// auditLoginFailure records a rejected sign-in. user may be only partly
+// known, e.g. just the username that was tried.
+func auditLoginFailure(c *gin.Context, user database.UserModel, reason string) {
+	metrics.RecordLogin(false)
+
+	entry := database.AuditEntryModel{
+		Action:    "auth.login",
+		ActorID:   user.ID,
+		ActorName: user.Username,
+		Result:    database.AuditResultFailure,
+		Detail:    reason,
+	}
+	if user.ID != 0 {
+		entry.TargetType, entry.TargetID = "user", strconv.Itoa(user.ID)
+	}
+	recordAudit(c, entry)
+}
+
+// auditFilter reads the filters shared by the audit list and export
+// endpoints, writing a 400 if one is malformed.
+func auditFilter(c *gin.Context) (database.AuditFilter, bool) {
+	filter := database.AuditFilter{
+		Action:     c.Query("action"),
+		TargetType: c.Query("targetType"),
+		TargetID:   c.Query("targetId"),
+		Result:     c.Query("result"),
+	}
+
+	if value := c.Query("actorId"); value != "" {
+		actorID, err := strconv.Atoi(value)
+		if err != nil {
+			problem.Abort(c, problem.InvalidParameter("actorId"))
+			return database.AuditFilter{}, false
+		}
+		filter.ActorID = actorID
+	}
+
+	for _, bound := range []struct {
+		name  string
+		value *time.Time
+	}{{"from", &filter.From}, {"to", &filter.To}} {
+		if value := c.Query(bound.name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				problem.Abort(c, problem.InvalidParameter(bound.name))
+				return database.AuditFilter{}, false
+			}
+			*bound.value = parsed
+		}
+	}
+
+	return filter, true
+}
+
+func (uc *UserController) ListAudit(c *gin.Context) {
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		problem.Abort(c, problem.InvalidParameter("page"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUsersPageSize)))
+	if err != nil || limit < 1 || limit > maxUsersPageSize {
+		problem.Abort(c, problem.InvalidParameter("limit"))
+		return
+	}
+
+	entries, total, err := database.QueryAuditLog(c.Request.Context(), filter, page, limit)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.AuditPage{
+		Entries: entries,
+		Total:   total,
+		Page:    page,
+		Limit:   limit,
+	})
+}
+
+// ExportAudit streams every matching entry, oldest first, as CSV or as
+// newline-delimited JSON. The hashes are included so the export can be
+// checked independently.
+func (uc *UserController) ExportAudit(c *gin.Context) {
+	filter, ok := auditFilter(c)
+	if !ok {
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		problem.Abort(c, problem.InvalidParameter("format"))
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "audit.export", Detail: "format=" + format})
+
+	// The server's write timeout is sized for ordinary responses; the export
+	// is bounded by the database query instead.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Now().Add(database.AuditExportTimeout)); err != nil {
+		slog.WarnContext(c.Request.Context(), "could not extend write deadline for audit export", "error", err)
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="audit-log.`+format+`"`)
+
+	var err error
+	var csvWriter *csv.Writer
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		csvWriter = csv.NewWriter(c.Writer)
+		csvWriter.Write([]string{"id", "time", "actorId", "actorName", "action", "targetType", "targetId", "workspaceId", "ip", "result", "detail", "prevHash", "hash"})
+		err = database.ExportAuditLog(c.Request.Context(), filter, func(e database.AuditEntryModel) error {
+			return csvWriter.Write(csvRecord(
+				strconv.Itoa(e.ID), e.Time.Format(time.RFC3339Nano), strconv.Itoa(e.ActorID), e.ActorName,
+				e.Action, e.TargetType, e.TargetID, strconv.Itoa(e.WorkspaceID), e.IP, e.Result, e.Detail,
+				e.PrevHash, e.Hash,
+			))
+		})
+	} else {
+		c.Header("Content-Type", "application/x-ndjson")
+		encoder := json.NewEncoder(c.Writer)
+		err = database.ExportAuditLog(c.Request.Context(), filter, func(e database.AuditEntryModel) error {
+			return encoder.Encode(e)
+		})
+	}
+
+	// Until the first bytes go out the failure can still be reported; the
+	// CSV writer buffers, so a query that fails at once sends nothing.
+	if err != nil && !c.Writer.Written() {
+		c.Writer.Header().Del("Content-Type")
+		c.Writer.Header().Del("Content-Disposition")
+		problem.Abort(c, err)
+		return
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+	}
+
+	// The status line has already been sent, so a failure can only be
+	// logged; the truncated file will not verify.
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "error exporting audit log", "error", err)
+	}
+}
+
+// csvRecord returns the cells with a ' put in front of any that a
+// spreadsheet would run as a formula, so an actor name or detail such as
+// "=HYPERLINK(...)" stays text. Cells starting with ' are escaped too, so
+// removing one leading ' always restores the value.
+func csvRecord(cells ...string) []string {
+	for i, cell := range cells {
+		if cell != "" && strings.ContainsRune("=+-@\t\r'", rune(cell[0])) {
+			cells[i] = "'" + cell
+		}
+	}
+	return cells
+}
+
+func (uc *UserController) VerifyAudit(c *gin.Context) {
+	result, err := database.VerifyAuditChain(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
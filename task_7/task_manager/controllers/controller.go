@@ -1,11 +1,21 @@
 package controllers
 
 import (
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
 	"strconv"
+	"task_manager/config"
 	database "task_manager/data"
+	"task_manager/mailer"
+	"task_manager/metrics"
 	"task_manager/middleware"
 	"task_manager/models"
+	"task_manager/passwordpolicy"
+	"task_manager/problem"
+	"task_manager/sso"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -19,24 +29,30 @@ func NewTaskController() *TaskController {
 func (tc *TaskController) CreateTask(c *gin.Context) {
 	var task database.TaskModel
 	if err := c.ShouldBindJSON(&task); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	createdTask := database.CreateTask(task)
+	createdTask, err := database.CreateTask(c.Request.Context(), c.GetInt("workspace_id"), c.GetInt("user_id"), task)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "task.create", TargetType: "task", TargetID: strconv.Itoa(createdTask.ID)})
 	c.JSON(http.StatusCreated, createdTask)
 }
 
 func (tc *TaskController) GetTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
-	task, found := database.GetTaskByID(id)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	task, err := database.GetTaskByID(c.Request.Context(), c.GetInt("workspace_id"), id)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
@@ -44,126 +60,629 @@ func (tc *TaskController) GetTask(c *gin.Context) {
 }
 
 func (tc *TaskController) GetAllTasks(c *gin.Context) {
-	tasks := database.GetAllTasks()
+	tasks, err := database.GetAllTasks(c.Request.Context(), c.GetInt("workspace_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, tasks)
 }
 
 func (tc *TaskController) UpdateTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
 	var updatedTask database.TaskModel
 	if err := c.ShouldBindJSON(&updatedTask); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	task, found := database.UpdateTask(id, updatedTask)
-	if !found {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	task, err := database.UpdateTask(c.Request.Context(), c.GetInt("workspace_id"), id, updatedTask)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
+	recordAudit(c, database.AuditEntryModel{Action: "task.update", TargetType: "task", TargetID: strconv.Itoa(id)})
+
 	c.JSON(http.StatusOK, task)
 }
 
 func (tc *TaskController) DeleteTask(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid task ID"})
+		problem.Abort(c, problem.InvalidParameter("id"))
 		return
 	}
 
-	deleted := database.DeleteTask(id)
-	if !deleted {
-		c.JSON(http.StatusNotFound, gin.H{"error": "task not found"})
+	if err := database.DeleteTask(c.Request.Context(), c.GetInt("workspace_id"), id); err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "task deleted successfully"})
+	recordAudit(c, database.AuditEntryModel{Action: "task.delete", TargetType: "task", TargetID: strconv.Itoa(id)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "task deleted successfully"})
 }
 
-type AuthController struct{}
+type AuthController struct {
+	openRegistration bool
+	sessionTTL       time.Duration
+	mailer           mailer.Mailer
+	passwordPolicy   *passwordpolicy.Policy
+	guard            loginGuard
+	// sso is nil when OIDC sign-in is not configured.
+	sso *sso.Client
+}
 
-func NewAuthController() *AuthController {
-	return &AuthController{}
+// NewAuthController takes from auth whether anyone may call /auth/register;
+// when registration is closed, new accounts can only be created by accepting
+// an invitation.
+func NewAuthController(auth config.Auth, login config.Login, m mailer.Mailer, policy *passwordpolicy.Policy, ssoClient *sso.Client) *AuthController {
+	return &AuthController{
+		openRegistration: auth.OpenRegistration,
+		sessionTTL:       auth.SessionTTL,
+		mailer:           m,
+		passwordPolicy:   policy,
+		guard:            newLoginGuard(login),
+		sso:              ssoClient,
+	}
 }
 
 func (ac *AuthController) Register(c *gin.Context) {
 	var req models.RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
 		return
 	}
 
-	user, err := database.CreateUser(req.Username, req.Password)
-	if err != nil {
-		if err.Error() == "username already exists" {
-			c.JSON(http.StatusConflict, gin.H{"error": "username already exists"})
+	if !ac.openRegistration {
+		hasUsers, err := database.HasUsers(c.Request.Context())
+		if err != nil {
+			problem.Abort(c, err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
+		if hasUsers {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeRegistrationClosed))
+			return
+		}
+	}
+
+	if rejectWeakPassword(c, ac.passwordPolicy, "password", req.Username, req.Password) {
+		return
+	}
+
+	user, err := database.CreateUser(c.Request.Context(), req.Username, req.Password, req.Email)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"id":       user.ID,
-		"username": user.Username,
-		"role":     user.Role,
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "auth.register",
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	if user.Email != "" {
+		if err := sendVerificationEmail(c.Request.Context(), ac.mailer, user); err != nil {
+			slog.ErrorContext(c.Request.Context(), "error sending verification email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, models.RegisterResponse{
+		ID:            user.ID,
+		Username:      user.Username,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Role:          user.Role,
 	})
 }
 
 func (ac *AuthController) Login(c *gin.Context) {
 	var req models.LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	attempt, ok := ac.guard.begin(c, req.Username)
+	if !ok {
 		return
 	}
+	defer attempt.release()
 
-	user, err := database.GetUserByUsername(req.Username)
+	user, err := database.GetUserByUsername(c.Request.Context(), req.Username)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		database.SimulatePasswordCheck(req.Password)
+		attempt.fail()
+		auditLoginFailure(c, database.UserModel{Username: req.Username}, "unknown username")
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
 		return
 	}
 
 	if !database.VerifyPassword(user.Password, req.Password) {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
+		attempt.fail()
+		auditLoginFailure(c, user, "wrong password")
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+
+	if user.Disabled {
+		auditLoginFailure(c, user, "account disabled")
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled))
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		// The username counter is only cleared once the second factor
+		// succeeds, so codes are throttled by the same lockout.
+		challenge, err := middleware.GenerateTwoFactorChallenge(user.ID)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
+	attempt.succeed()
+	ac.issueToken(c, user, "password")
+}
+
+// LoginTwoFactor exchanges a challenge token from Login plus a TOTP or
+// recovery code for a session token.
+func (ac *AuthController) LoginTwoFactor(c *gin.Context) {
+	var req models.LoginTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	userID, err := middleware.ValidateTwoFactorChallenge(req.ChallengeToken)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidChallenge))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), userID)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil || user.Disabled || !user.TwoFactorEnabled {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidChallenge))
+		return
+	}
+
+	attempt, ok := ac.guard.begin(c, user.Username)
+	if !ok {
+		return
+	}
+	defer attempt.release()
+
+	method, reason, valid := "totp", "wrong 2fa code", false
+	if req.RecoveryCode != "" {
+		method, reason = "recovery code", "wrong recovery code"
+		err = database.ConsumeRecoveryCode(c.Request.Context(), user.ID, req.RecoveryCode)
+		valid = err == nil
+		if errors.Is(err, database.ErrNotFound) {
+			err = nil
+		}
+	} else {
+		valid, err = verifyTOTP(c.Request.Context(), user, req.Code)
+	}
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if !valid {
+		attempt.fail()
+		auditLoginFailure(c, user, reason)
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCode))
+		return
+	}
+
+	attempt.succeed()
+	ac.issueToken(c, user, method)
+}
+
+// issueToken starts a new session for user on the requesting device and
+// returns its login token. method names how the user authenticated, for the
+// audit log.
+func (ac *AuthController) issueToken(c *gin.Context, user database.UserModel, method string) {
+	session, err := database.CreateSession(c.Request.Context(), user.ID, c.Request.UserAgent(), c.ClientIP(), ac.sessionTTL)
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
-	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role)
+	token, err := middleware.GenerateToken(user.ID, user.Username, user.Role, user.TokenVersion, session.ID, session.ExpiresAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
+		problem.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"token":    token,
-		"username": user.Username,
-		"role":     user.Role,
+	metrics.RecordLogin(true)
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "auth.login",
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		TargetType: "session",
+		TargetID:   strconv.Itoa(session.ID),
+		Detail:     "method=" + method,
+	})
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token:    token,
+		Username: user.Username,
+		Role:     user.Role,
 	})
 }
 
-func (ac *AuthController) Promote(c *gin.Context) {
-	var req models.PromoteRequest
+func (ac *AuthController) AcceptInvitation(c *gin.Context) {
+	var req models.AcceptInvitationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	claims, err := middleware.ValidateInvitationToken(req.Token)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidInvitation))
+		return
+	}
+
+	invitation, err := database.GetInvitationByID(c.Request.Context(), claims.InvitationID)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil || invitation.Email != claims.Email || invitation.Accepted || !invitation.ExpiresAt.After(time.Now()) {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidInvitation))
+		return
+	}
+
+	existingUser, err := database.GetUserByUsername(c.Request.Context(), req.Username)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	linking := err == nil
+	if linking && !database.VerifyPassword(existingUser.Password, req.Password) {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+	if linking && existingUser.Disabled {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled))
+		return
+	}
+
+	if !linking && rejectWeakPassword(c, ac.passwordPolicy, "password", req.Username, req.Password) {
 		return
 	}
 
-	err := database.PromoteUser(req.Username)
+	// From here on, finish joining or undo the new account even if the
+	// client hangs up.
+	ctx := context.WithoutCancel(c.Request.Context())
+	instanceRole := "user"
+	if invitation.WorkspaceID == 0 {
+		instanceRole = invitation.Role
+	}
+
+	// The account is created before the invitation is used up, so a failed
+	// signup can be retried with the same token. Marking it accepted only
+	// succeeds while it is pending, which keeps it single-use; the loser of
+	// a race has its new account removed again.
+	user := existingUser
+	if !linking {
+		user, err = database.CreateInvitedUser(ctx, req.Username, req.Password, invitation.Email, instanceRole)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+	}
+
+	err = database.MarkInvitationAccepted(ctx, invitation.ID)
 	if err != nil {
-		if err.Error() == "user not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		if !linking {
+			if err := database.DeleteUser(ctx, user.ID); err != nil {
+				slog.ErrorContext(ctx, "error removing account after a failed invitation", "user_id", user.ID, "error", err)
+			}
+		}
+		if serverError(err) {
+			problem.Abort(c, err)
+		} else {
+			problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidInvitation))
+		}
+		return
+	}
+
+	if linking && instanceRole == "admin" && user.Role != "admin" {
+		if err := database.PromoteUser(ctx, user.Username); err != nil {
+			problem.Abort(c, err)
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to promote user"})
+		user.Role = "admin"
+	}
+
+	if invitation.WorkspaceID != 0 {
+		err := database.AddWorkspaceMember(ctx, invitation.WorkspaceID, user.ID, invitation.Role)
+		if err != nil && !errors.Is(err, database.ErrConflict) {
+			problem.Abort(c, err)
+			return
+		}
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:      "auth.accept_invitation",
+		ActorID:     user.ID,
+		ActorName:   user.Username,
+		TargetType:  "invitation",
+		TargetID:    strconv.Itoa(invitation.ID),
+		WorkspaceID: invitation.WorkspaceID,
+		Detail:      "role=" + invitation.Role,
+	})
+
+	status := http.StatusCreated
+	if linking {
+		status = http.StatusOK
+	}
+
+	c.JSON(status, models.AcceptInvitationResponse{
+		ID:          user.ID,
+		Username:    user.Username,
+		Role:        user.Role,
+		WorkspaceID: invitation.WorkspaceID,
+	})
+}
+
+// ForgotPassword always answers the same way, whether or not the email
+// belongs to an account, so it cannot be used to discover users.
+func (ac *AuthController) ForgotPassword(c *gin.Context) {
+	var req models.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByVerifiedEmail(c.Request.Context(), req.Email)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err == nil && !user.Disabled {
+		if err := sendPasswordResetEmail(c.Request.Context(), ac.mailer, user); err != nil {
+			slog.ErrorContext(c.Request.Context(), "error sending password reset email", "user_id", user.ID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "if the email belongs to a verified account, a reset link has been sent"})
+}
+
+func (ac *AuthController) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	// Check the policy before using up the token so a rejected password
+	// can be retried with the same email.
+	pending, err := database.GetUserToken(c.Request.Context(), req.Token, database.TokenPurposePasswordReset)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidToken))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), pending.UserID)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidToken))
+		return
+	}
+
+	if rejectWeakPassword(c, ac.passwordPolicy, "newPassword", user.Username, req.NewPassword) {
+		return
+	}
+
+	token, err := database.ConsumeUserToken(c.Request.Context(), req.Token, database.TokenPurposePasswordReset)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidToken))
+		return
+	}
+
+	// The token is used up, so finish the reset even if the client hangs up.
+	ctx := context.WithoutCancel(c.Request.Context())
+	if _, err := database.ChangePassword(ctx, token.UserID, req.NewPassword); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if _, err := database.RevokeAllSessions(ctx, token.UserID, 0); err != nil {
+		slog.ErrorContext(ctx, "error revoking sessions", "user_id", token.UserID, "error", err)
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "auth.password_reset",
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+	})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "password reset successfully"})
+}
+
+func (ac *AuthController) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	token, err := database.ConsumeUserToken(c.Request.Context(), req.Token, database.TokenPurposeEmailVerification)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidToken))
+		return
+	}
+
+	err = database.MarkEmailVerified(c.Request.Context(), token.UserID, token.Email)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidToken))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "email verified successfully"})
+}
+
+func (ac *AuthController) Promote(c *gin.Context) {
+	var req models.PromoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	err := database.PromoteUser(c.Request.Context(), req.Username)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	promoted, err := database.GetUserByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	recordAudit(c, database.AuditEntryModel{Action: "user.promote", TargetType: "user", TargetID: strconv.Itoa(promoted.ID)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "user promoted to admin successfully"})
+}
+
+type WorkspaceController struct{}
+
+func NewWorkspaceController() *WorkspaceController {
+	return &WorkspaceController{}
+}
+
+func (wc *WorkspaceController) CreateWorkspace(c *gin.Context) {
+	var req models.CreateWorkspaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	workspace, err := database.CreateWorkspace(c.Request.Context(), req.Name, c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, workspace)
+}
+
+func (wc *WorkspaceController) GetMyWorkspaces(c *gin.Context) {
+	workspaces, err := database.GetWorkspacesForUser(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, workspaces)
+}
+
+func (wc *WorkspaceController) GetWorkspace(c *gin.Context) {
+	workspace, err := database.GetWorkspaceByID(c.Request.Context(), c.GetInt("workspace_id"))
+	if err != nil {
+		problem.Abort(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "user promoted to admin successfully"})
+	c.JSON(http.StatusOK, workspace)
 }
 
+func (wc *WorkspaceController) AddMember(c *gin.Context) {
+	var req models.AddWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByUsername(c.Request.Context(), req.Username)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = database.WorkspaceRoleMember
+	}
+
+	err = database.AddWorkspaceMember(c.Request.Context(), c.GetInt("workspace_id"), user.ID, role)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "workspace.add_member",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Detail:     "role=" + role,
+	})
+
+	c.JSON(http.StatusCreated, database.WorkspaceMember{UserID: user.ID, Role: role})
+}
+
+func (wc *WorkspaceController) RemoveMember(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("userId"))
+		return
+	}
+
+	err = database.RemoveWorkspaceMember(c.Request.Context(), c.GetInt("workspace_id"), userID)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "workspace.remove_member", TargetType: "user", TargetID: strconv.Itoa(userID)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "workspace member removed successfully"})
+}
@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	"errors"
+
+	database "task_manager/data"
+)
+
+// serverError reports whether err is a failure on the server's side, rather
+// than a missing record or a conflict that the caller may describe in its
+// own terms.
+func serverError(err error) bool {
+	return err != nil && !errors.Is(err, database.ErrNotFound) && !errors.Is(err, database.ErrConflict)
+}
@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthController struct{}
+
+func NewHealthController() *HealthController {
+	return &HealthController{}
+}
+
+// Healthz reports that the process is up and serving. It does not touch
+// MongoDB, so a database outage does not get the process restarted.
+func (hc *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{Status: "ok"})
+}
+
+// Readyz reports whether the server can handle requests, which needs
+// MongoDB to be reachable.
+func (hc *HealthController) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+
+	if err := database.Ping(ctx); err != nil {
+		slog.WarnContext(ctx, "readiness check failed", "error", err)
+		c.JSON(http.StatusServiceUnavailable, models.HealthResponse{Status: "unavailable", Error: "database unreachable"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.HealthResponse{Status: "ready"})
+}
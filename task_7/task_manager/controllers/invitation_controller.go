@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/mailer"
+	"task_manager/middleware"
+	"task_manager/models"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type InvitationController struct {
+	mailer mailer.Mailer
+}
+
+func NewInvitationController(m mailer.Mailer) *InvitationController {
+	return &InvitationController{mailer: m}
+}
+
+func (ic *InvitationController) issue(c *gin.Context, invitation database.InvitationModel) {
+	invitation.InvitedBy = c.GetInt("user_id")
+	invitation.ExpiresAt = time.Now().Add(invitationTTL)
+
+	created, err := database.CreateInvitation(c.Request.Context(), invitation)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	token, err := middleware.GenerateInvitationToken(created.ID, created.Email, created.ExpiresAt)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	sendInvitationEmail(c.Request.Context(), ic.mailer, created, token)
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:      "invitation.create",
+		TargetType:  "invitation",
+		TargetID:    strconv.Itoa(created.ID),
+		WorkspaceID: created.WorkspaceID,
+		Detail:      "email=" + created.Email + " role=" + created.Role,
+	})
+
+	c.JSON(http.StatusCreated, models.CreateInvitationResponse{
+		Invitation: created,
+		Token:      token,
+	})
+}
+
+func (ic *InvitationController) CreateInvitation(c *gin.Context) {
+	var req models.InviteUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = "user"
+	}
+
+	ic.issue(c, database.InvitationModel{Email: req.Email, Role: role})
+}
+
+func (ic *InvitationController) GetInvitations(c *gin.Context) {
+	invitations, err := database.GetPendingInvitations(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (ic *InvitationController) RevokeInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return
+	}
+
+	ic.revoke(c, id)
+}
+
+func (ic *InvitationController) CreateWorkspaceInvitation(c *gin.Context) {
+	var req models.InviteWorkspaceMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = database.WorkspaceRoleMember
+	}
+
+	ic.issue(c, database.InvitationModel{
+		Email:       req.Email,
+		WorkspaceID: c.GetInt("workspace_id"),
+		Role:        role,
+	})
+}
+
+func (ic *InvitationController) GetWorkspaceInvitations(c *gin.Context) {
+	invitations, err := database.GetPendingWorkspaceInvitations(c.Request.Context(), c.GetInt("workspace_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+func (ic *InvitationController) RevokeWorkspaceInvitation(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return
+	}
+
+	invitation, err := database.GetInvitationByID(c.Request.Context(), id)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err != nil || invitation.WorkspaceID != c.GetInt("workspace_id") {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeInvitationNotFound))
+		return
+	}
+
+	ic.revoke(c, id)
+}
+
+func (ic *InvitationController) revoke(c *gin.Context, id int) {
+	err := database.RevokeInvitation(c.Request.Context(), id)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "invitation.revoke", TargetType: "invitation", TargetID: strconv.Itoa(id)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "invitation revoked successfully"})
+}
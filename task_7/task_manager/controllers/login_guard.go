@@ -0,0 +1,195 @@
+package controllers
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task_manager/config"
+	database "task_manager/data"
+	"task_manager/models"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginGuard throttles password and 2FA guessing. Every failure is counted
+// against both the username and the client IP. Each failure below the
+// threshold adds an exponentially growing delay before the next attempt is
+// accepted; reaching the threshold locks the subject out for lockoutDuration.
+// Counters restart when no failure happened within window.
+//
+// Attempts are reserved before the password is checked, and only as many
+// may be in flight as there are failures left before the threshold, so
+// parallel guesses cannot overshoot it.
+type loginGuard struct {
+	usernameThreshold int
+	ipThreshold       int
+	backoffBase       time.Duration
+	lockoutDuration   time.Duration
+	window            time.Duration
+}
+
+func newLoginGuard(cfg config.Login) loginGuard {
+	return loginGuard{
+		usernameThreshold: cfg.LockoutThreshold,
+		ipThreshold:       cfg.IPLockoutThreshold,
+		backoffBase:       cfg.BackoffBase,
+		lockoutDuration:   cfg.LockoutDuration,
+		window:            cfg.FailureWindow,
+	}
+}
+
+func (g loginGuard) subjects(c *gin.Context, username string) [][2]string {
+	return [][2]string{
+		{database.LoginAttemptKindUsername, strings.ToLower(username)},
+		{database.LoginAttemptKindIP, c.ClientIP()},
+	}
+}
+
+func (g loginGuard) threshold(kind string) int {
+	if kind == database.LoginAttemptKindIP {
+		return g.ipThreshold
+	}
+	return g.usernameThreshold
+}
+
+// loginAttempt is an attempt reserved by loginGuard.begin. Its outcome is
+// reported with fail or succeed; release, which callers defer, hands the
+// reservation back if neither was called, as when the request fails for
+// another reason.
+type loginAttempt struct {
+	guard    loginGuard
+	c        *gin.Context
+	subjects [][2]string
+	done     bool
+}
+
+// begin reserves an attempt for the username and the client IP. If either
+// is blocked, or already has as many attempts in flight as it has failures
+// left, it writes a 429 with Retry-After and returns false.
+func (g loginGuard) begin(c *gin.Context, username string) (*loginAttempt, bool) {
+	attempt := &loginAttempt{guard: g, c: c}
+	ctx := context.WithoutCancel(c.Request.Context())
+
+	var retryAfter time.Duration
+	for _, subject := range g.subjects(c, username) {
+		counter, err := database.ReserveLoginAttempt(ctx, subject[0], subject[1], g.window)
+		if err != nil {
+			attempt.release()
+			problem.Abort(c, err)
+			return nil, false
+		}
+		attempt.subjects = append(attempt.subjects, subject)
+
+		if wait := time.Until(counter.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+		// After a lockout ends one attempt at a time is let through.
+		if counter.Pending > max(1, g.threshold(subject[0])-counter.Failures) {
+			retryAfter = max(retryAfter, g.backoffBase, time.Second)
+		}
+	}
+
+	if retryAfter <= 0 {
+		return attempt, true
+	}
+
+	attempt.release()
+	auditLoginFailure(c, database.UserModel{Username: username}, "throttled")
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeTooManyAttempts))
+	return nil, false
+}
+
+func (a *loginAttempt) fail() {
+	a.done = true
+	// Hanging up early must not keep a failure from being counted.
+	ctx := context.WithoutCancel(a.c.Request.Context())
+	g := a.guard
+	for _, subject := range a.subjects {
+		counter, err := database.RecordLoginFailure(ctx, subject[0], subject[1])
+		if err != nil {
+			slog.ErrorContext(ctx, "error recording failed login", "kind", subject[0], "subject", subject[1], "error", err)
+			continue
+		}
+
+		delay := g.lockoutDuration
+		if counter.Failures < g.threshold(subject[0]) {
+			delay = g.backoffBase << (counter.Failures - 1)
+			if delay > g.lockoutDuration || delay <= 0 {
+				delay = g.lockoutDuration
+			}
+		}
+
+		if err := database.SetLoginLockedUntil(ctx, subject[0], subject[1], time.Now().Add(delay)); err != nil {
+			slog.ErrorContext(ctx, "error locking out login", "kind", subject[0], "subject", subject[1], "error", err)
+		}
+	}
+}
+
+// succeed clears the username counter. The IP counter is left to expire on
+// its own so a valid login cannot be used to reset guessing from that IP.
+func (a *loginAttempt) succeed() {
+	a.end(true)
+}
+
+func (a *loginAttempt) release() {
+	if !a.done {
+		a.end(false)
+	}
+}
+
+func (a *loginAttempt) end(success bool) {
+	a.done = true
+	ctx := context.WithoutCancel(a.c.Request.Context())
+	for _, subject := range a.subjects {
+		reset := success && subject[0] == database.LoginAttemptKindUsername
+		if err := database.ReleaseLoginAttempt(ctx, subject[0], subject[1], reset); err != nil {
+			slog.ErrorContext(ctx, "error releasing login attempt", "kind", subject[0], "subject", subject[1], "error", err)
+		}
+	}
+}
+
+func (uc *UserController) GetLockouts(c *gin.Context) {
+	lockouts, err := database.GetActiveLoginLockouts(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, lockouts)
+}
+
+// ClearLockout removes the failure counter for the username or ip given as a
+// query parameter.
+func (uc *UserController) ClearLockout(c *gin.Context) {
+	kind, subject := database.LoginAttemptKindUsername, strings.ToLower(c.Query("username"))
+	if ip := c.Query("ip"); ip != "" {
+		kind, subject = database.LoginAttemptKindIP, ip
+	}
+
+	if subject == "" {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeMissingParameter).With("name", "username or ip"))
+		return
+	}
+
+	cleared, err := database.ClearLoginFailures(c.Request.Context(), kind, subject)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if !cleared {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeLockoutNotFound))
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "lockout.clear", TargetType: kind, TargetID: subject})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "lockout cleared successfully"})
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/mailer"
+)
+
+const (
+	passwordResetTTL     = time.Hour
+	emailVerificationTTL = 24 * time.Hour
+)
+
+func sendVerificationEmail(ctx context.Context, m mailer.Mailer, user database.UserModel) error {
+	token, err := database.CreateUserToken(ctx, user.ID, database.TokenPurposeEmailVerification, user.Email, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return m.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm this address by sending the token below to POST /auth/verify-email within %s:\n\n%s\n",
+			user.Username, emailVerificationTTL, token),
+	})
+}
+
+func sendPasswordResetEmail(ctx context.Context, m mailer.Mailer, user database.UserModel) error {
+	token, err := database.CreateUserToken(ctx, user.ID, database.TokenPurposePasswordReset, user.Email, passwordResetTTL)
+	if err != nil {
+		return err
+	}
+
+	return m.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset your password. Send the token below to POST /auth/reset-password within %s:\n\n%s\n\nIf this wasn't you, ignore this email.\n",
+			user.Username, passwordResetTTL, token),
+	})
+}
+
+func sendInvitationEmail(ctx context.Context, m mailer.Mailer, invitation database.InvitationModel, token string) {
+	target := "the task manager"
+	if invitation.WorkspaceID != 0 {
+		target = fmt.Sprintf("workspace %d", invitation.WorkspaceID)
+	}
+
+	err := m.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: "You have been invited to the task manager",
+		Body: fmt.Sprintf("You have been invited to join %s as %s. Accept by sending the token below to POST /auth/accept-invitation before %s:\n\n%s\n",
+			target, invitation.Role, invitation.ExpiresAt.Format(time.RFC1123), token),
+	})
+	if err != nil {
+		// The token is also returned to the inviting admin, so a mail
+		// failure is not fatal to the request.
+		slog.ErrorContext(ctx, "error sending invitation email", "invitation_id", invitation.ID, "error", err)
+	}
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/passwordpolicy"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rejectWeakPassword aborts with a 400 listing every broken rule against
+// field, and returns true, if password does not satisfy policy.
+func rejectWeakPassword(c *gin.Context, policy *passwordpolicy.Policy, field, username, password string) bool {
+	violations := policy.Validate(username, password)
+	if len(violations) == 0 {
+		return false
+	}
+
+	p := problem.New(http.StatusBadRequest, problem.CodeWeakPassword)
+	for _, violation := range violations {
+		fieldErr := problem.FieldError{Field: field, Code: violation.Rule}
+		if violation.Limit > 0 {
+			fieldErr.Param = strconv.Itoa(violation.Limit)
+		}
+		p.Errors = append(p.Errors, fieldErr)
+	}
+	problem.Abort(c, p)
+	return true
+}
+
+// reauthWindow is how recently an account without a password must have
+// signed in for the session to stand in for the password.
+const reauthWindow = 5 * time.Minute
+
+// checkPassword reports whether password confirms the caller's identity.
+// Accounts created by SSO have no password; for them the current session
+// must have started within reauthWindow, so the user proves who they are by
+// signing in at the identity provider again. Otherwise the error asks them
+// to.
+func checkPassword(c *gin.Context, user database.UserModel, password string) (bool, error) {
+	if user.Password != "" {
+		return database.VerifyPassword(user.Password, password), nil
+	}
+
+	session, err := database.GetActiveSession(c.Request.Context(), user.ID, c.GetInt("session_id"))
+	if err != nil {
+		return false, err
+	}
+	if time.Since(session.CreatedAt) > reauthWindow {
+		minutes := strconv.Itoa(int(reauthWindow.Minutes()))
+		return false, problem.New(http.StatusUnauthorized, problem.CodeReauthenticationRequired).With("minutes", minutes)
+	}
+	return true, nil
+}
@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"task_manager/problem"
+
+	database "task_manager/data"
+	"task_manager/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func (ac *AccountController) ListSessions(c *gin.Context) {
+	sessions, err := database.GetActiveSessions(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	current := c.GetInt("session_id")
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == current
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession signs one of the caller's devices out. Revoking the current
+// session is the same as logging out.
+func (ac *AccountController) RevokeSession(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("sessionId"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("sessionId"))
+		return
+	}
+
+	if err := database.RevokeSession(c.Request.Context(), c.GetInt("user_id"), id); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "session.revoke", TargetType: "session", TargetID: strconv.Itoa(id)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "session revoked successfully"})
+}
+
+// RevokeUserSessions lets an admin sign a user out of every device. API
+// tokens are not affected.
+func (uc *UserController) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return
+	}
+
+	if _, err := database.GetUserByID(c.Request.Context(), id); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	revoked, err := database.RevokeAllSessions(c.Request.Context(), id, 0)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "user.revoke_sessions",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(id),
+		Detail:     "revoked=" + strconv.FormatInt(revoked, 10),
+	})
+
+	c.JSON(http.StatusOK, models.RevokeSessionsResponse{
+		Message: "sessions revoked successfully",
+		Revoked: revoked,
+	})
+}
@@ -0,0 +1,256 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/middleware"
+	"task_manager/models"
+	"task_manager/problem"
+	"task_manager/sso"
+
+	"github.com/gin-gonic/gin"
+)
+
+const oidcStateCookie = "oidc_state"
+
+// SSOLogin starts the OIDC authorization code flow by redirecting the
+// browser to the identity provider.
+func (ac *AuthController) SSOLogin(c *gin.Context) {
+	url, err := ac.startSSO(c, 0)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, url)
+}
+
+// SSOLink starts the same flow for the signed-in caller, whose account the
+// identity is linked to at the callback. This is the only way to link an
+// account that sign-in by email would not link on its own. The browser must
+// then be sent to the returned URL, since the flow needs the state cookie
+// set here.
+func (ac *AuthController) SSOLink(c *gin.Context) {
+	url, err := ac.startSSO(c, c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SSOLinkResponse{AuthorizationURL: url})
+}
+
+// startSSO keeps the values for one sign-in in the state cookie and returns
+// the identity provider URL to send the browser to.
+func (ac *AuthController) startSSO(c *gin.Context, linkUserID int) (string, error) {
+	state, nonce, verifier := sso.NewLoginAttempt()
+
+	cookie, err := middleware.GenerateOIDCState(state, nonce, verifier, linkUserID)
+	if err != nil {
+		return "", err
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, cookie, int((10 * time.Minute).Seconds()), "/auth/oidc", "", c.Request.TLS != nil, true)
+	return ac.sso.AuthCodeURL(state, nonce, verifier), nil
+}
+
+// SSOCallback completes the flow: it checks state, redeems the code with the
+// PKCE verifier, maps the verified identity to a local account and issues
+// the same token as a password login, or the same 2FA challenge. A flow
+// started by SSOLink links the identity instead.
+func (ac *AuthController) SSOCallback(c *gin.Context) {
+	if idpError := c.Query("error"); idpError != "" {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeSSOFailed).Extend("providerError", idpError))
+		return
+	}
+
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err != nil {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidSSOState))
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, "/auth/oidc", "", c.Request.TLS != nil, true)
+
+	saved, err := middleware.ValidateOIDCState(cookie)
+	if err != nil || saved.State != c.Query("state") {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidSSOState))
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	identity, err := ac.sso.Exchange(ctx, c.Query("code"), saved.CodeVerifier, saved.Nonce)
+	if err != nil {
+		slog.WarnContext(ctx, "error completing OIDC sign-in", "error", err)
+		auditLoginFailure(c, database.UserModel{}, "sso token could not be verified")
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeSSONotVerified))
+		return
+	}
+
+	if saved.LinkUserID != 0 {
+		ac.linkSSOIdentity(c, saved.LinkUserID, identity)
+		return
+	}
+
+	user, err := ac.resolveSSOUser(c.Request.Context(), identity)
+	if err != nil {
+		auditLoginFailure(c, database.UserModel{Username: identity.Username}, "sso: "+err.Error())
+		problem.Abort(c, err)
+		return
+	}
+
+	if user.Disabled {
+		auditLoginFailure(c, user, "account disabled")
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled))
+		return
+	}
+
+	if role, ok := ac.sso.Role(identity); ok && role != user.Role && !user.ServiceAccount {
+		user = ac.syncSSORole(c, user, role)
+	}
+
+	if user.TwoFactorEnabled {
+		challenge, err := middleware.GenerateTwoFactorChallenge(user.ID)
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, models.LoginResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+		})
+		return
+	}
+
+	ac.issueToken(c, user, "sso")
+}
+
+// linkSSOIdentity adds identity to the account of userID, which started the
+// flow from a signed-in session.
+func (ac *AuthController) linkSSOIdentity(c *gin.Context, userID int, identity sso.Identity) {
+	user, err := database.GetUserByID(c.Request.Context(), userID)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if user.Disabled {
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAccountDisabled))
+		return
+	}
+
+	linked, err := database.GetUserByExternalIdentity(c.Request.Context(), identity.Issuer, identity.Subject)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return
+	}
+	if err == nil && linked.ID != user.ID {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeSSOIdentityInUse))
+		return
+	}
+
+	external := database.ExternalIdentity{Issuer: identity.Issuer, Subject: identity.Subject}
+	if err := database.LinkExternalIdentity(c.Request.Context(), user.ID, external); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "account.link_sso",
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Detail:     "issuer=" + identity.Issuer,
+	})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "identity provider account linked"})
+}
+
+// resolveSSOUser finds the account for identity: first by the linked IdP
+// subject, then by verified email (linking it, except for admin, 2FA,
+// disabled and service accounts), and finally by provisioning a new account
+// if allowed.
+func (ac *AuthController) resolveSSOUser(ctx context.Context, identity sso.Identity) (database.UserModel, error) {
+	external := database.ExternalIdentity{Issuer: identity.Issuer, Subject: identity.Subject}
+
+	user, err := database.GetUserByExternalIdentity(ctx, external.Issuer, external.Subject)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, database.ErrNotFound) {
+		return database.UserModel{}, err
+	}
+
+	if identity.EmailVerified && identity.Email != "" {
+		user, err := database.GetUserByVerifiedEmail(ctx, identity.Email)
+		if err == nil {
+			// Whoever controls the address at the provider would get the
+			// account, so accounts worth more than that must link from a
+			// signed-in session.
+			if user.Role == "admin" || user.TwoFactorEnabled || user.Disabled || user.ServiceAccount {
+				return database.UserModel{}, problem.New(http.StatusForbidden, problem.CodeSSOLinkRequired)
+			}
+			if err := database.LinkExternalIdentity(ctx, user.ID, external); err != nil {
+				return database.UserModel{}, err
+			}
+			return user, nil
+		}
+		if !errors.Is(err, database.ErrNotFound) {
+			return database.UserModel{}, err
+		}
+	}
+
+	if !ac.sso.AutoProvision() {
+		return database.UserModel{}, problem.New(http.StatusForbidden, problem.CodeSSOAccountNotLinked)
+	}
+
+	username := identity.Username
+	if username == "" {
+		username = identity.Subject
+	}
+
+	role, ok := ac.sso.Role(identity)
+	if !ok {
+		role = "user"
+	}
+
+	user, err = database.CreateExternalUser(ctx, username, identity.Email, identity.EmailVerified, role, external)
+	if err != nil {
+		return database.UserModel{}, err
+	}
+
+	return user, nil
+}
+
+// syncSSORole applies the role from IdP group mapping, except that it never
+// demotes the last active admin.
+func (ac *AuthController) syncSSORole(c *gin.Context, user database.UserModel, role string) database.UserModel {
+	updated, err := database.UpdateUser(c.Request.Context(), user.ID, &role, nil)
+	if errors.Is(err, database.ErrConflict) {
+		return user
+	}
+	if err != nil {
+		slog.ErrorContext(c.Request.Context(), "error syncing role from SSO groups", "user_id", user.ID, "error", err)
+		return user
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "user.update",
+		ActorID:    user.ID,
+		ActorName:  user.Username,
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Detail:     "role=" + role + " (from sso groups)",
+	})
+
+	return updated
+}
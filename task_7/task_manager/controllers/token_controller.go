@@ -0,0 +1,178 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/models"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultAPITokenLifetimeDays = 90
+
+func createAPIToken(c *gin.Context, owner database.UserModel) {
+	var req models.CreateAPITokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	for _, scope := range req.Scopes {
+		if scope == database.ScopeAdmin && owner.Role != "admin" {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAdminScopeNotAllowed))
+			return
+		}
+	}
+
+	days := req.ExpiresInDays
+	if days == 0 {
+		days = defaultAPITokenLifetimeDays
+	}
+
+	apiToken, token, err := database.CreateAPIToken(c.Request.Context(), owner.ID, req.Name, req.Scopes, time.Now().AddDate(0, 0, days))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "api_token.create",
+		TargetType: "api_token",
+		TargetID:   strconv.Itoa(apiToken.ID),
+		Detail:     fmt.Sprintf("owner=%d scopes=%s", owner.ID, strings.Join(apiToken.Scopes, ",")),
+	})
+
+	c.JSON(http.StatusCreated, models.CreateAPITokenResponse{
+		APIToken: apiToken,
+		Token:    token,
+	})
+}
+
+func listAPITokens(c *gin.Context, ownerID int) {
+	tokens, err := database.GetAPITokensForUser(c.Request.Context(), ownerID)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func revokeAPIToken(c *gin.Context, ownerID int) {
+	id, err := strconv.Atoi(c.Param("tokenId"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("tokenId"))
+		return
+	}
+
+	if err := database.RevokeAPIToken(c.Request.Context(), ownerID, id); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "api_token.revoke",
+		TargetType: "api_token",
+		TargetID:   strconv.Itoa(id),
+		Detail:     fmt.Sprintf("owner=%d", ownerID),
+	})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "token revoked successfully"})
+}
+
+func (ac *AccountController) CreateToken(c *gin.Context) {
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	createAPIToken(c, user)
+}
+
+func (ac *AccountController) ListTokens(c *gin.Context) {
+	listAPITokens(c, c.GetInt("user_id"))
+}
+
+func (ac *AccountController) RevokeToken(c *gin.Context) {
+	revokeAPIToken(c, c.GetInt("user_id"))
+}
+
+func (uc *UserController) CreateServiceAccount(c *gin.Context) {
+	var req models.CreateServiceAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.CreateServiceAccount(c.Request.Context(), req.Name, req.Role)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "service_account.create",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(user.ID),
+		Detail:     "role=" + user.Role,
+	})
+
+	c.JSON(http.StatusCreated, user)
+}
+
+func (uc *UserController) ListServiceAccounts(c *gin.Context) {
+	users, err := database.GetServiceAccounts(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// serviceAccount loads the :id service account, writing a 404 if the ID
+// does not belong to one.
+func (uc *UserController) serviceAccount(c *gin.Context) (database.UserModel, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return database.UserModel{}, false
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), id)
+	if serverError(err) {
+		problem.Abort(c, err)
+		return database.UserModel{}, false
+	}
+	if err != nil || !user.ServiceAccount {
+		problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeServiceAccountNotFound))
+		return database.UserModel{}, false
+	}
+
+	return user, true
+}
+
+func (uc *UserController) CreateServiceAccountToken(c *gin.Context) {
+	if user, ok := uc.serviceAccount(c); ok {
+		createAPIToken(c, user)
+	}
+}
+
+func (uc *UserController) ListServiceAccountTokens(c *gin.Context) {
+	if user, ok := uc.serviceAccount(c); ok {
+		listAPITokens(c, user.ID)
+	}
+}
+
+func (uc *UserController) RevokeServiceAccountToken(c *gin.Context) {
+	if user, ok := uc.serviceAccount(c); ok {
+		revokeAPIToken(c, user.ID)
+	}
+}
@@ -0,0 +1,219 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	database "task_manager/data"
+	"task_manager/models"
+	"task_manager/problem"
+	"task_manager/totp"
+
+	"github.com/gin-gonic/gin"
+)
+
+const totpIssuer = "Task Manager"
+
+// verifyTOTP checks code against the user's active secret and records its
+// time step, so the same code cannot be used twice. An error means the step
+// could not be recorded.
+func verifyTOTP(ctx context.Context, user database.UserModel, code string) (bool, error) {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	err := database.RecordTOTPStep(ctx, user.ID, step)
+	if errors.Is(err, database.ErrConflict) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (ac *AccountController) EnrollTwoFactor(c *gin.Context) {
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if user.TwoFactorEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeTwoFactorAlreadyEnabled))
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if err := database.SetPendingTOTPSecret(c.Request.Context(), user.ID, secret); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.EnrollTwoFactorResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(totpIssuer, user.Username, secret),
+	})
+}
+
+func (ac *AccountController) ConfirmTwoFactor(c *gin.Context) {
+	var req models.ConfirmTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if user.PendingTOTPSecret == "" {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeNoPendingEnrollment))
+		return
+	}
+
+	// A stolen session must not be able to try all million codes.
+	attempt, ok := ac.guard.begin(c, user.Username)
+	if !ok {
+		return
+	}
+	defer attempt.release()
+
+	step, ok := totp.Validate(user.PendingTOTPSecret, req.Code, time.Now())
+	if !ok {
+		attempt.fail()
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeInvalidCode))
+		return
+	}
+	attempt.succeed()
+
+	recoveryCodes, err := database.EnableTOTP(c.Request.Context(), user.ID, user.PendingTOTPSecret, step)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "account.enable_2fa", TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+
+	c.JSON(http.StatusOK, models.ConfirmTwoFactorResponse{
+		Message:       "two-factor authentication enabled",
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+func (ac *AccountController) DisableTwoFactor(c *gin.Context) {
+	var req models.DisableTwoFactorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), c.GetInt("user_id"))
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if !user.TwoFactorEnabled {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeTwoFactorNotEnabled))
+		return
+	}
+
+	// The policy is checked first, so a refused request does not use up the
+	// code.
+	if user.Role == "admin" {
+		policy, err := database.GetSecurityPolicy(c.Request.Context())
+		if err != nil {
+			problem.Abort(c, err)
+			return
+		}
+		if policy.RequireAdminTwoFactor {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeTwoFactorRequired))
+			return
+		}
+	}
+
+	attempt, ok := ac.guard.begin(c, user.Username)
+	if !ok {
+		return
+	}
+	defer attempt.release()
+
+	confirmed, err := checkPassword(c, user, req.Password)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if !confirmed {
+		attempt.fail()
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+
+	valid, err := verifyTOTP(c.Request.Context(), user, req.Code)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	if !valid {
+		attempt.fail()
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidCredentials))
+		return
+	}
+	attempt.succeed()
+
+	if err := database.DisableTOTP(c.Request.Context(), user.ID); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{Action: "account.disable_2fa", TargetType: "user", TargetID: strconv.Itoa(user.ID)})
+
+	c.JSON(http.StatusOK, models.MessageResponse{Message: "two-factor authentication disabled"})
+}
+
+func (uc *UserController) GetSecurityPolicy(c *gin.Context) {
+	policy, err := database.GetSecurityPolicy(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, policy)
+}
+
+func (uc *UserController) UpdateSecurityPolicy(c *gin.Context) {
+	var req models.SecurityPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	// Turning the requirement on without 2FA would immediately lock the
+	// caller out of every admin endpoint, including this one.
+	if *req.RequireAdminTwoFactor && !c.GetBool("two_factor_enabled") {
+		problem.Abort(c, problem.New(http.StatusConflict, problem.CodeEnableTwoFactorFirst))
+		return
+	}
+
+	policy := database.SecurityPolicy{RequireAdminTwoFactor: *req.RequireAdminTwoFactor}
+	if err := database.UpdateSecurityPolicy(c.Request.Context(), policy); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action: "security_policy.update",
+		Detail: "requireAdminTwoFactor=" + strconv.FormatBool(policy.RequireAdminTwoFactor),
+	})
+
+	c.JSON(http.StatusOK, policy)
+}
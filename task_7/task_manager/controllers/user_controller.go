@@ -0,0 +1,247 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	database "task_manager/data"
+	"task_manager/models"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultUsersPageSize = 20
+	maxUsersPageSize     = 100
+)
+
+type UserController struct{}
+
+func NewUserController() *UserController {
+	return &UserController{}
+}
+
+func (uc *UserController) ListUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		problem.Abort(c, problem.InvalidParameter("page"))
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultUsersPageSize)))
+	if err != nil || limit < 1 || limit > maxUsersPageSize {
+		problem.Abort(c, problem.InvalidParameter("limit"))
+		return
+	}
+
+	users, total, err := database.ListUsers(c.Request.Context(), c.Query("search"), page, limit)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.UserPage{
+		Users: users,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	})
+}
+
+func (uc *UserController) UpdateUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return
+	}
+
+	var req models.UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Abort(c, problem.FromBindError(err))
+		return
+	}
+
+	updated, err := database.UpdateUser(c.Request.Context(), id, req.Role, req.Disabled)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	// Re-enabling an account should not bring its old logins back.
+	if updated.Disabled {
+		if _, err := database.RevokeAllSessions(c.Request.Context(), id, 0); err != nil {
+			slog.ErrorContext(c.Request.Context(), "error revoking sessions", "user_id", id, "error", err)
+		}
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "user.update",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(id),
+		Detail:     fmt.Sprintf("role=%s disabled=%t", updated.Role, updated.Disabled),
+	})
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteUser removes an account and hands its tasks to the user given in the
+// reassignTo query parameter, or to the admin making the request, who must
+// be a member of every workspace the tasks are in.
+func (uc *UserController) DeleteUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, problem.InvalidParameter("id"))
+		return
+	}
+
+	reassignTo := c.GetInt("user_id")
+	if value := c.Query("reassignTo"); value != "" {
+		reassignTo, err = strconv.Atoi(value)
+		if err != nil {
+			problem.Abort(c, problem.InvalidParameter("reassignTo"))
+			return
+		}
+	}
+	if reassignTo == id {
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeReassignToDeletedUser))
+		return
+	}
+
+	user, err := database.GetUserByID(c.Request.Context(), id)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if _, err := database.GetUserByID(c.Request.Context(), reassignTo); err != nil {
+		if serverError(err) {
+			problem.Abort(c, err)
+			return
+		}
+		problem.Abort(c, problem.New(http.StatusBadRequest, problem.CodeUserNotFound))
+		return
+	}
+
+	workspaces, err := database.GetWorkspacesForUser(c.Request.Context(), id)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	// Tasks stay in their workspace, so they may only go to a member of it.
+	tasks, err := database.GetTasksByOwner(c.Request.Context(), id)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+	checked := map[int]bool{}
+	for _, task := range tasks {
+		if checked[task.WorkspaceID] {
+			continue
+		}
+		checked[task.WorkspaceID] = true
+		_, err := database.GetWorkspaceRole(c.Request.Context(), task.WorkspaceID, reassignTo)
+		if serverError(err) {
+			problem.Abort(c, err)
+			return
+		}
+		if err != nil {
+			problem.Abort(c, problem.New(http.StatusConflict, problem.CodeReassignToNonMember).Extend("workspaceId", task.WorkspaceID))
+			return
+		}
+	}
+
+	// Once removal has started it runs to the end even if the client hangs
+	// up, so the account is not left half deleted.
+	ctx := context.WithoutCancel(c.Request.Context())
+	if !retireAccount(c, ctx, user, workspaces) {
+		return
+	}
+
+	reassigned, err := database.ReassignTasks(ctx, id, reassignTo)
+	if err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if err := database.RevokeAllAPITokens(ctx, id); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	if err := database.DeleteUser(ctx, id); err != nil {
+		problem.Abort(c, err)
+		return
+	}
+
+	recordAudit(c, database.AuditEntryModel{
+		Action:     "user.delete",
+		TargetType: "user",
+		TargetID:   strconv.Itoa(id),
+		Detail:     fmt.Sprintf("username=%s reassignedTo=%d", user.Username, reassignTo),
+	})
+
+	c.JSON(http.StatusOK, models.DeleteUserResponse{
+		Message:         "user deleted successfully",
+		ReassignedTo:    reassignTo,
+		ReassignedTasks: reassigned,
+	})
+}
+
+// retireAccount is the first step of deleting an account: it disables the
+// account, so it no longer counts as an active admin, and takes it out of
+// every workspace. The data layer refuses either step if it would leave the
+// instance or a workspace without an admin; the steps already taken are then
+// undone, the error response is written and false is returned.
+func retireAccount(c *gin.Context, ctx context.Context, user database.UserModel, workspaces []database.WorkspaceModel) bool {
+	disabled := true
+	if _, err := database.UpdateUser(ctx, user.ID, nil, &disabled); err != nil {
+		problem.Abort(c, err)
+		return false
+	}
+
+	// left maps the workspaces already left to the role held in them.
+	left := map[int]string{}
+	for _, workspace := range workspaces {
+		err := database.RemoveWorkspaceMember(ctx, workspace.ID, user.ID)
+		if err == nil {
+			left[workspace.ID] = memberRole(workspace, user.ID)
+			continue
+		}
+		if errors.Is(err, database.ErrNotFound) {
+			// The workspace or the membership is already gone.
+			continue
+		}
+
+		for workspaceID, role := range left {
+			if undoErr := database.AddWorkspaceMember(ctx, workspaceID, user.ID, role); undoErr != nil {
+				slog.ErrorContext(ctx, "error restoring workspace membership", "user_id", user.ID, "workspace_id", workspaceID, "error", undoErr)
+			}
+		}
+		if _, undoErr := database.UpdateUser(ctx, user.ID, nil, &user.Disabled); undoErr != nil {
+			slog.ErrorContext(ctx, "error re-enabling user", "user_id", user.ID, "error", undoErr)
+		}
+
+		if errors.Is(err, database.ErrConflict) {
+			err = problem.New(http.StatusConflict, problem.CodeLastWorkspaceAdmin).Extend("workspaceId", workspace.ID)
+		}
+		problem.Abort(c, err)
+		return false
+	}
+
+	return true
+}
+
+func memberRole(workspace database.WorkspaceModel, userID int) string {
+	for _, member := range workspace.Members {
+		if member.UserID == userID {
+			return member.Role
+		}
+	}
+	return ""
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APITokenPrefix marks personal access tokens so AuthMiddleware can tell
+// them apart from JWTs without a database lookup.
+const APITokenPrefix = "tm_"
+
+const (
+	ScopeTasksRead       = "tasks:read"
+	ScopeTasksWrite      = "tasks:write"
+	ScopeWorkspacesWrite = "workspaces:write"
+	ScopeAdmin           = "admin"
+)
+
+// lastUsedResolution limits how often LastUsedAt is written, so a busy CI
+// job does not turn every request into a database write.
+const lastUsedResolution = time.Minute
+
+type APITokenModel struct {
+	ID     int    `json:"id" bson:"id"`
+	UserID int    `json:"userId" bson:"userId"`
+	Name   string `json:"name" bson:"name"`
+	// Hint is the start of the token, enough to recognise it in a list.
+	Hint       string     `json:"hint" bson:"hint"`
+	TokenHash  string     `json:"-" bson:"tokenHash"`
+	Scopes     []string   `json:"scopes" bson:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt" bson:"createdAt"`
+	ExpiresAt  time.Time  `json:"expiresAt" bson:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty" bson:"lastUsedAt,omitempty"`
+	Revoked    bool       `json:"revoked" bson:"revoked"`
+}
+
+var apiTokenCollection *mongo.Collection
+
+// getNextAPITokenID takes IDs from a counter, so concurrent creates never
+// share one and revoking a token by ID cannot hit another.
+func getNextAPITokenID(ctx context.Context) (int, error) {
+	var last APITokenModel
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	err := apiTokenCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, wrapError(err)
+	}
+	return nextSequence(ctx, "api_tokens", last.ID)
+}
+
+// CreateAPIToken stores a new token for userID and returns the model along
+// with the raw token, which is not stored and cannot be retrieved again.
+func CreateAPIToken(ctx context.Context, userID int, name string, scopes []string, expiresAt time.Time) (APITokenModel, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return APITokenModel{}, "", wrapError(err)
+	}
+	token := APITokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	nextID, err := getNextAPITokenID(ctx)
+	if err != nil {
+		return APITokenModel{}, "", wrapError(err)
+	}
+
+	apiToken := APITokenModel{
+		ID:        nextID,
+		UserID:    userID,
+		Name:      name,
+		Hint:      token[:len(APITokenPrefix)+6],
+		TokenHash: hashUserToken(token),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+
+	_, err = apiTokenCollection.InsertOne(ctx, apiToken)
+	if err != nil {
+		return APITokenModel{}, "", wrapError(err)
+	}
+
+	return apiToken, token, nil
+}
+
+// GetActiveAPIToken resolves a raw token to its record if it is neither
+// revoked nor expired, and records that it was used.
+func GetActiveAPIToken(ctx context.Context, token string) (APITokenModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"tokenHash": hashUserToken(token),
+		"revoked":   false,
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	var apiToken APITokenModel
+	err := apiTokenCollection.FindOne(ctx, filter).Decode(&apiToken)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return APITokenModel{}, &Error{Kind: ErrNotFound, Code: "invalid_token", Message: "invalid or expired token"}
+	}
+	if err != nil {
+		return APITokenModel{}, wrapError(err)
+	}
+
+	if apiToken.LastUsedAt == nil || now.Sub(*apiToken.LastUsedAt) > lastUsedResolution {
+		_, err := apiTokenCollection.UpdateOne(ctx, bson.M{"id": apiToken.ID}, bson.M{"$set": bson.M{"lastUsedAt": now}})
+		if err != nil {
+			return APITokenModel{}, wrapError(err)
+		}
+		apiToken.LastUsedAt = &now
+	}
+
+	return apiToken, nil
+}
+
+func GetAPITokensForUser(ctx context.Context, userID int) ([]APITokenModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := apiTokenCollection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	tokens := []APITokenModel{}
+	if err := cursor.All(ctx, &tokens); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return tokens, nil
+}
+
+// RevokeAPIToken revokes one of userID's tokens. Revoked tokens are kept so
+// they still show up, with their last use, in the owner's token list.
+func RevokeAPIToken(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"id": id, "userId": userID}
+	result, err := apiTokenCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("token")
+	}
+
+	return nil
+}
+
+func RevokeAllAPITokens(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := apiTokenCollection.UpdateMany(ctx, bson.M{"userId": userID}, bson.M{"$set": bson.M{"revoked": true}})
+	return wrapError(err)
+}
+
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, APITokenPrefix)
+}
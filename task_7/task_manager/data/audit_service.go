@@ -0,0 +1,255 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	AuditResultSuccess = "success"
+	AuditResultFailure = "failure"
+
+	// AuditExportTimeout bounds a full export of the log.
+	AuditExportTimeout = 5 * time.Minute
+)
+
+// AuditEntryModel is one record in the append-only audit log. Each entry
+// stores the hash of the one before it, so editing or removing an entry
+// breaks the chain from that point on; see VerifyAuditChain.
+type AuditEntryModel struct {
+	ID   int       `json:"id" bson:"id"`
+	Time time.Time `json:"time" bson:"time"`
+	// ActorID is 0 when nobody is signed in, e.g. for a failed login.
+	ActorID     int    `json:"actorId" bson:"actorId"`
+	ActorName   string `json:"actorName,omitempty" bson:"actorName,omitempty"`
+	Action      string `json:"action" bson:"action"`
+	TargetType  string `json:"targetType,omitempty" bson:"targetType,omitempty"`
+	TargetID    string `json:"targetId,omitempty" bson:"targetId,omitempty"`
+	WorkspaceID int    `json:"workspaceId,omitempty" bson:"workspaceId,omitempty"`
+	IP          string `json:"ip,omitempty" bson:"ip,omitempty"`
+	Result      string `json:"result" bson:"result"`
+	Detail      string `json:"detail,omitempty" bson:"detail,omitempty"`
+	PrevHash    string `json:"prevHash" bson:"prevHash"`
+	Hash        string `json:"hash" bson:"hash"`
+}
+
+// AuditFilter narrows an audit query; zero fields match everything.
+type AuditFilter struct {
+	ActorID    int
+	Action     string
+	TargetType string
+	TargetID   string
+	Result     string
+	From       time.Time
+	To         time.Time
+}
+
+// AuditVerification is the outcome of walking the hash chain. HeadHash can
+// be recorded elsewhere to detect later truncation of the newest entries,
+// which the chain alone cannot reveal.
+type AuditVerification struct {
+	Entries  int64  `json:"entries"`
+	Valid    bool   `json:"valid"`
+	BrokenAt int    `json:"brokenAt,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	HeadHash string `json:"headHash,omitempty"`
+}
+
+var (
+	auditCollection *mongo.Collection
+	// auditMu avoids needless insert conflicts within one process; the
+	// unique index on id keeps the chain linear across processes. It is
+	// held only for the read of the last entry and the insert, but every
+	// audited request in the process queues on it for those two round
+	// trips.
+	auditMu sync.Mutex
+)
+
+func (e AuditEntryModel) computeHash() string {
+	fields := struct {
+		ID          int    `json:"id"`
+		Time        string `json:"time"`
+		ActorID     int    `json:"actorId"`
+		ActorName   string `json:"actorName"`
+		Action      string `json:"action"`
+		TargetType  string `json:"targetType"`
+		TargetID    string `json:"targetId"`
+		WorkspaceID int    `json:"workspaceId"`
+		IP          string `json:"ip"`
+		Result      string `json:"result"`
+		Detail      string `json:"detail"`
+		PrevHash    string `json:"prevHash"`
+	}{
+		e.ID, e.Time.UTC().Format(time.RFC3339Nano), e.ActorID, e.ActorName, e.Action,
+		e.TargetType, e.TargetID, e.WorkspaceID, e.IP, e.Result, e.Detail, e.PrevHash,
+	}
+
+	encoded, _ := json.Marshal(fields)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
+// AppendAudit links entry to the end of the chain and stores it. ID, Time,
+// PrevHash and Hash are set here.
+func AppendAudit(ctx context.Context, entry AuditEntryModel) (AuditEntryModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	auditMu.Lock()
+	defer auditMu.Unlock()
+
+	for attempt := 0; attempt < 5; attempt++ {
+		var last AuditEntryModel
+		opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+		err := auditCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return AuditEntryModel{}, wrapError(err)
+		}
+
+		entry.ID = last.ID + 1
+		entry.PrevHash = last.Hash
+		// MongoDB keeps milliseconds, so hash what will be read back.
+		entry.Time = time.Now().UTC().Truncate(time.Millisecond)
+		entry.Hash = entry.computeHash()
+
+		_, err = auditCollection.InsertOne(ctx, entry)
+		if mongo.IsDuplicateKeyError(err) {
+			continue
+		}
+		if err != nil {
+			return AuditEntryModel{}, wrapError(err)
+		}
+
+		return entry, nil
+	}
+
+	return AuditEntryModel{}, &Error{Kind: ErrUnavailable, Code: "service_unavailable", Message: "audit log is busy"}
+}
+
+func auditQuery(filter AuditFilter) bson.M {
+	query := bson.M{}
+	if filter.ActorID != 0 {
+		query["actorId"] = filter.ActorID
+	}
+	if filter.Action != "" {
+		query["action"] = filter.Action
+	}
+	if filter.TargetType != "" {
+		query["targetType"] = filter.TargetType
+	}
+	if filter.TargetID != "" {
+		query["targetId"] = filter.TargetID
+	}
+	if filter.Result != "" {
+		query["result"] = filter.Result
+	}
+
+	timeRange := bson.M{}
+	if !filter.From.IsZero() {
+		timeRange["$gte"] = filter.From
+	}
+	if !filter.To.IsZero() {
+		timeRange["$lt"] = filter.To
+	}
+	if len(timeRange) > 0 {
+		query["time"] = timeRange
+	}
+
+	return query
+}
+
+// QueryAuditLog returns one page of matching entries, newest first, and the
+// total number of matches.
+func QueryAuditLog(ctx context.Context, filter AuditFilter, page, limit int) ([]AuditEntryModel, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	query := auditQuery(filter)
+	total, err := auditCollection.CountDocuments(ctx, query)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "id", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := auditCollection.Find(ctx, query, opts)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	entries := []AuditEntryModel{}
+	if err := cursor.All(ctx, &entries); err != nil {
+		return nil, 0, wrapError(err)
+	}
+
+	return entries, total, nil
+}
+
+// ExportAuditLog streams every matching entry, oldest first, to fn.
+func ExportAuditLog(ctx context.Context, filter AuditFilter, fn func(AuditEntryModel) error) error {
+	ctx, cancel := context.WithTimeout(ctx, AuditExportTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := auditCollection.Find(ctx, auditQuery(filter), opts)
+	if err != nil {
+		return wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry AuditEntryModel
+		if err := cursor.Decode(&entry); err != nil {
+			return wrapError(err)
+		}
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+
+	return wrapError(cursor.Err())
+}
+
+// VerifyAuditChain recomputes every hash and checks that each entry links to
+// the one before it with no gaps in the IDs.
+func VerifyAuditChain(ctx context.Context) (AuditVerification, error) {
+	var result AuditVerification
+	var prev AuditEntryModel
+
+	err := ExportAuditLog(ctx, AuditFilter{}, func(entry AuditEntryModel) error {
+		result.Entries++
+
+		if result.BrokenAt == 0 {
+			switch {
+			case entry.ID != prev.ID+1:
+				result.BrokenAt, result.Reason = entry.ID, "entries missing before this one"
+			case entry.PrevHash != prev.Hash:
+				result.BrokenAt, result.Reason = entry.ID, "previous hash does not match"
+			case entry.Hash != entry.computeHash():
+				result.BrokenAt, result.Reason = entry.ID, "entry was modified"
+			}
+		}
+
+		prev = entry
+		return nil
+	})
+	if err != nil {
+		return AuditVerification{}, err
+	}
+
+	result.Valid = result.BrokenAt == 0
+	result.HeadHash = prev.Hash
+	return result, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"task_manager/config"
+	"task_manager/logging"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// client is shared by every store in this package.
+var client *mongo.Client
+
+// maxRetryDelay caps the wait between connection attempts at startup.
+const maxRetryDelay = 10 * time.Second
+
+// Connect opens the MongoDB client and waits, retrying with backoff until
+// cfg.StartupTimeout or ctx ends, for the server to answer. It must succeed
+// before any other function in this package is called.
+func Connect(ctx context.Context, cfg config.Database) error {
+	opts, err := clientOptions(cfg)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("connecting to MongoDB", "url", logging.Redact(cfg.URL))
+
+	c, err := mongo.Connect(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("connect to MongoDB: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.StartupTimeout)
+	defer cancel()
+
+	delay := 500 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err = c.Ping(ctx, readpref.Primary())
+		if err == nil {
+			break
+		}
+		slog.Warn("MongoDB is not reachable yet", "attempt", attempt, "retry_in", delay.String(), "error", err)
+
+		select {
+		case <-ctx.Done():
+			c.Disconnect(context.Background())
+			return fmt.Errorf("ping MongoDB: %w", err)
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
+	}
+
+	client = c
+	db := client.Database(cfg.Name)
+	taskCollection = db.Collection(cfg.Collections.Tasks)
+	userCollection = db.Collection(cfg.Collections.Users)
+	counterCollection = db.Collection(cfg.Collections.Counters)
+	workspaceCollection = db.Collection(cfg.Collections.Workspaces)
+	invitationCollection = db.Collection(cfg.Collections.Invitations)
+	userTokenCollection = db.Collection(cfg.Collections.UserTokens)
+	settingsCollection = db.Collection(cfg.Collections.Settings)
+	loginAttemptCollection = db.Collection(cfg.Collections.LoginAttempts)
+	apiTokenCollection = db.Collection(cfg.Collections.APITokens)
+	sessionCollection = db.Collection(cfg.Collections.Sessions)
+	auditCollection = db.Collection(cfg.Collections.AuditLog)
+
+	if err := ensureIndexes(ctx); err != nil {
+		return err
+	}
+	if err := migrateUnscopedTasks(ctx); err != nil {
+		return fmt.Errorf("move tasks into the default workspace: %w", err)
+	}
+
+	return nil
+}
+
+// clientOptions applies the URL first, so that the explicit settings in cfg
+// take precedence over its query parameters.
+func clientOptions(cfg config.Database) (*options.ClientOptions, error) {
+	opts := options.Client().ApplyURI(cfg.URL).SetMonitor(commandMonitor())
+
+	if cfg.MaxPoolSize > 0 {
+		opts.SetMaxPoolSize(uint64(cfg.MaxPoolSize))
+	}
+	if cfg.MinPoolSize > 0 {
+		opts.SetMinPoolSize(uint64(cfg.MinPoolSize))
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		opts.SetMaxConnIdleTime(cfg.MaxConnIdleTime)
+	}
+	if cfg.ConnectTimeout > 0 {
+		opts.SetConnectTimeout(cfg.ConnectTimeout)
+	}
+	if cfg.ServerSelectionTimeout > 0 {
+		opts.SetServerSelectionTimeout(cfg.ServerSelectionTimeout)
+	}
+
+	if cfg.Username != "" || cfg.AuthMechanism != "" {
+		opts.SetAuth(options.Credential{
+			AuthMechanism: cfg.AuthMechanism,
+			AuthSource:    cfg.AuthSource,
+			Username:      cfg.Username,
+			Password:      cfg.Password,
+			PasswordSet:   cfg.Password != "",
+		})
+	}
+
+	if cfg.TLS {
+		tlsConfig, err := tlsConfig(cfg)
+		if err != nil {
+			return nil, err
+		}
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	if err := opts.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid MongoDB options: %w", err)
+	}
+	return opts, nil
+}
+
+func tlsConfig(cfg config.Database) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecure,
+	}
+
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read MongoDB CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.TLSCertKeyFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCertKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("read MongoDB client certificate: %w", err)
+		}
+		cert, err := tls.X509KeyPair(pem, pem)
+		if err != nil {
+			return nil, fmt.Errorf("load MongoDB client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Ping checks that MongoDB is reachable, for the readiness probe.
+func Ping(ctx context.Context) error {
+	return client.Ping(ctx, readpref.Primary())
+}
+
+// Disconnect closes the MongoDB client once the server has stopped taking
+// requests.
+func Disconnect(ctx context.Context) error {
+	if client == nil {
+		return nil
+	}
+	return client.Disconnect(ctx)
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var counterCollection *mongo.Collection
+
+type counter struct {
+	Seq int `bson:"seq"`
+}
+
+// nextSequence returns the next value of a named counter that never hands
+// out the same number twice, even after documents are deleted. floor is the
+// highest ID already in use, so existing data is respected the first time a
+// counter is used.
+func nextSequence(ctx context.Context, name string, floor int) (int, error) {
+	raise := func() error {
+		_, err := counterCollection.UpdateOne(ctx,
+			bson.M{"_id": name},
+			bson.M{"$max": bson.M{"seq": floor}},
+			options.Update().SetUpsert(true))
+		return err
+	}
+	// Two first uses can race to insert the counter; the loser finds it
+	// in place on the second try.
+	err := raise()
+	if mongo.IsDuplicateKeyError(err) {
+		err = raise()
+	}
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var next counter
+	err = counterCollection.FindOneAndUpdate(ctx, bson.M{"_id": name}, bson.M{"$inc": bson.M{"seq": 1}}, opts).Decode(&next)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	return next.Seq, nil
+}
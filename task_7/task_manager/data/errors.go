@@ -0,0 +1,75 @@
+package database
+
+import (
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned by this package wrap one of these, so callers can tell
+// the kinds of failure apart with errors.Is. Anything else is unexpected.
+var (
+	ErrNotFound = errors.New("not found")
+	// ErrConflict means the request clashes with the current state, such
+	// as a duplicate name or an invitation that was already used.
+	ErrConflict = errors.New("conflict")
+	// ErrUnavailable means MongoDB could not be reached in time; the same
+	// request may succeed later.
+	ErrUnavailable = errors.New("database unavailable")
+)
+
+// Error is a failure of a known kind. Code names the failure for API
+// clients, such as "user_not_found", and Message describes it; Error adds
+// the underlying cause, if any, for logs.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Cause   error
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return e.Message + ": " + e.Cause.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() []error {
+	if e.Cause != nil {
+		return []error{e.Kind, e.Cause}
+	}
+	return []error{e.Kind}
+}
+
+// notFound returns an ErrNotFound error for the named thing, such as
+// "user not found" with the code "user_not_found".
+func notFound(what string) error {
+	return &Error{
+		Kind:    ErrNotFound,
+		Code:    strings.ReplaceAll(what, " ", "_") + "_not_found",
+		Message: what + " not found",
+	}
+}
+
+func conflict(code, message string) error {
+	return &Error{Kind: ErrConflict, Code: code, Message: message}
+}
+
+// wrapError classifies an error from the driver. Errors of no known kind are
+// returned unchanged.
+func wrapError(err error) error {
+	var known *Error
+	switch {
+	case err == nil, errors.As(err, &known):
+		return err
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return &Error{Kind: ErrNotFound, Code: "not_found", Message: "not found", Cause: err}
+	case mongo.IsDuplicateKeyError(err):
+		return &Error{Kind: ErrConflict, Code: "conflict", Message: "already exists", Cause: err}
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err), errors.Is(err, mongo.ErrClientDisconnected):
+		return &Error{Kind: ErrUnavailable, Code: "service_unavailable", Message: "database unavailable", Cause: err}
+	}
+	return err
+}
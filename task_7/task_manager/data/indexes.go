@@ -0,0 +1,45 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ensureIndexes creates the unique indexes that the stores rely on to stay
+// consistent under concurrent requests. Creating an index that exists is a
+// no-op; one that cannot be built, for example because of duplicates left
+// by an older version, stops the server from starting.
+func ensureIndexes(ctx context.Context) error {
+	indexes := []struct {
+		collection *mongo.Collection
+		fields     []string
+	}{
+		{userCollection, []string{"id"}},
+		{userCollection, []string{"username"}},
+		{invitationCollection, []string{"id"}},
+		{apiTokenCollection, []string{"id"}},
+		// Requests are authenticated by the token's hash alone.
+		{apiTokenCollection, []string{"tokenHash"}},
+		// Login attempt counters are upserted by concurrent requests.
+		{loginAttemptCollection, []string{"kind", "subject"}},
+		// Each audit entry links to the one before it by ID.
+		{auditCollection, []string{"id"}},
+	}
+
+	for _, index := range indexes {
+		keys := bson.D{}
+		for _, field := range index.fields {
+			keys = append(keys, bson.E{Key: field, Value: 1})
+		}
+		model := mongo.IndexModel{Keys: keys, Options: options.Index().SetUnique(true)}
+		if _, err := index.collection.Indexes().CreateOne(ctx, model); err != nil {
+			return fmt.Errorf("create unique index on %s(%s): %w", index.collection.Name(), strings.Join(index.fields, ", "), wrapError(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// InvitationModel is an outstanding offer for someone to join the instance
+// (WorkspaceID == 0) or a single workspace. The token handed to the invitee
+// is signed separately and only carries the invitation ID.
+type InvitationModel struct {
+	ID          int       `json:"id" bson:"id"`
+	Email       string    `json:"email" bson:"email"`
+	WorkspaceID int       `json:"workspaceId,omitempty" bson:"workspaceId"`
+	Role        string    `json:"role" bson:"role"`
+	InvitedBy   int       `json:"invitedBy" bson:"invitedBy"`
+	CreatedAt   time.Time `json:"createdAt" bson:"createdAt"`
+	ExpiresAt   time.Time `json:"expiresAt" bson:"expiresAt"`
+	Accepted    bool      `json:"accepted" bson:"accepted"`
+}
+
+var invitationCollection *mongo.Collection
+
+// getNextInvitationID never reuses the ID of a revoked invitation, since the
+// tokens already handed out carry the ID.
+func getNextInvitationID(ctx context.Context) (int, error) {
+	var last InvitationModel
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	err := invitationCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, wrapError(err)
+	}
+	return nextSequence(ctx, "invitations", last.ID)
+}
+
+func pendingInvitationFilter() bson.M {
+	return bson.M{"accepted": false, "expiresAt": bson.M{"$gt": time.Now()}}
+}
+
+func CreateInvitation(ctx context.Context, invitation InvitationModel) (InvitationModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := pendingInvitationFilter()
+	filter["email"] = invitation.Email
+	filter["workspaceId"] = invitation.WorkspaceID
+	count, err := invitationCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return InvitationModel{}, wrapError(err)
+	}
+	if count > 0 {
+		return InvitationModel{}, conflict("invitation_pending", "pending invitation already exists")
+	}
+
+	nextID, err := getNextInvitationID(ctx)
+	if err != nil {
+		return InvitationModel{}, wrapError(err)
+	}
+	invitation.ID = nextID
+	invitation.CreatedAt = time.Now()
+	invitation.Accepted = false
+
+	_, err = invitationCollection.InsertOne(ctx, invitation)
+	if err != nil {
+		return InvitationModel{}, wrapError(err)
+	}
+
+	return invitation, nil
+}
+
+func GetInvitationByID(ctx context.Context, id int) (InvitationModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var invitation InvitationModel
+	err := invitationCollection.FindOne(ctx, bson.M{"id": id}).Decode(&invitation)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return InvitationModel{}, notFound("invitation")
+	}
+	if err != nil {
+		return InvitationModel{}, wrapError(err)
+	}
+
+	return invitation, nil
+}
+
+func findPendingInvitations(ctx context.Context, filter bson.M) ([]InvitationModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	for key, value := range pendingInvitationFilter() {
+		filter[key] = value
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := invitationCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	invitations := []InvitationModel{}
+	if err := cursor.All(ctx, &invitations); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return invitations, nil
+}
+
+// GetPendingInvitations lists every unaccepted, unexpired invitation on the
+// instance, including those scoped to a workspace.
+func GetPendingInvitations(ctx context.Context) ([]InvitationModel, error) {
+	return findPendingInvitations(ctx, bson.M{})
+}
+
+func GetPendingWorkspaceInvitations(ctx context.Context, workspaceID int) ([]InvitationModel, error) {
+	return findPendingInvitations(ctx, bson.M{"workspaceId": workspaceID})
+}
+
+// MarkInvitationAccepted flips the invitation to accepted exactly once, so a
+// token cannot be replayed to create a second account.
+func MarkInvitationAccepted(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := pendingInvitationFilter()
+	filter["id"] = id
+	update := bson.M{"$set": bson.M{"accepted": true}}
+
+	result, err := invitationCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return conflict("invitation_used", "invitation is no longer valid")
+	}
+
+	return nil
+}
+
+func RevokeInvitation(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := invitationCollection.DeleteOne(ctx, bson.M{"id": id, "accepted": false})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return notFound("invitation")
+	}
+
+	return nil
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	LoginAttemptKindUsername = "username"
+	LoginAttemptKindIP       = "ip"
+)
+
+// LoginAttemptModel counts recent failed logins for one username or one
+// client IP. LockedUntil is when the next attempt will be accepted. Pending
+// counts attempts that have been reserved but not yet checked.
+type LoginAttemptModel struct {
+	Kind        string    `json:"kind" bson:"kind"`
+	Subject     string    `json:"subject" bson:"subject"`
+	Failures    int       `json:"failures" bson:"failures"`
+	LastFailure time.Time `json:"lastFailure" bson:"lastFailure"`
+	LockedUntil time.Time `json:"lockedUntil" bson:"lockedUntil"`
+	Pending     int       `json:"-" bson:"pending"`
+	LastAttempt time.Time `json:"-" bson:"lastAttempt"`
+}
+
+// pendingTimeout is how long a reservation may stay open. Requests finish
+// long before; it only matters if a server dies while checking a password.
+const pendingTimeout = time.Minute
+
+var loginAttemptCollection *mongo.Collection
+
+func loginAttemptFilter(kind, subject string) bson.M {
+	return bson.M{"kind": kind, "subject": subject}
+}
+
+// ReserveLoginAttempt atomically counts an attempt as pending before the
+// password or code is checked, and returns the counter afterwards, so the
+// caller can refuse an attempt that would exceed the threshold together
+// with those already in flight. The failure count starts over if the last
+// failure is older than window. Every reservation must be ended with
+// RecordLoginFailure or ReleaseLoginAttempt.
+func ReserveLoginAttempt(ctx context.Context, kind, subject string, window time.Duration) (LoginAttemptModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	resets := []struct {
+		filter bson.M
+		field  string
+	}{
+		{bson.M{"lastFailure": bson.M{"$lt": now.Add(-window)}}, "failures"},
+		{bson.M{"lastAttempt": bson.M{"$lt": now.Add(-pendingTimeout)}}, "pending"},
+	}
+	for _, reset := range resets {
+		filter := loginAttemptFilter(kind, subject)
+		for key, value := range reset.filter {
+			filter[key] = value
+		}
+		if _, err := loginAttemptCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{reset.field: 0}}); err != nil {
+			return LoginAttemptModel{}, wrapError(err)
+		}
+	}
+
+	update := bson.M{
+		"$inc": bson.M{"pending": 1},
+		"$set": bson.M{"lastAttempt": now},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var attempt LoginAttemptModel
+	err := loginAttemptCollection.FindOneAndUpdate(ctx, loginAttemptFilter(kind, subject), update, opts).Decode(&attempt)
+	if err != nil {
+		return LoginAttemptModel{}, wrapError(err)
+	}
+
+	return attempt, nil
+}
+
+// RecordLoginFailure turns a reservation into a failure and returns the
+// counter afterwards.
+func RecordLoginFailure(ctx context.Context, kind, subject string) (LoginAttemptModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var attempt LoginAttemptModel
+	filter := loginAttemptFilter(kind, subject)
+	filter["pending"] = bson.M{"$gt": 0}
+	update := bson.M{"$inc": bson.M{"failures": 1, "pending": -1}, "$set": bson.M{"lastFailure": now}}
+	err := loginAttemptCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&attempt)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// The reservation expired or an admin cleared the counter while
+		// the attempt was checked; the failure still counts.
+		update := bson.M{"$inc": bson.M{"failures": 1}, "$set": bson.M{"lastFailure": now}}
+		err = loginAttemptCollection.FindOneAndUpdate(ctx, loginAttemptFilter(kind, subject), update, opts.SetUpsert(true)).Decode(&attempt)
+	}
+	if err != nil {
+		return LoginAttemptModel{}, wrapError(err)
+	}
+
+	return attempt, nil
+}
+
+// ReleaseLoginAttempt ends a reservation without a failure. With reset it
+// also clears the failure count and any lockout, for a successful login.
+func ReleaseLoginAttempt(ctx context.Context, kind, subject string, reset bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	if reset {
+		update := bson.M{"$set": bson.M{"failures": 0, "lockedUntil": time.Time{}}}
+		if _, err := loginAttemptCollection.UpdateOne(ctx, loginAttemptFilter(kind, subject), update); err != nil {
+			return wrapError(err)
+		}
+	}
+
+	filter := loginAttemptFilter(kind, subject)
+	filter["pending"] = bson.M{"$gt": 0}
+	_, err := loginAttemptCollection.UpdateOne(ctx, filter, bson.M{"$inc": bson.M{"pending": -1}})
+	return wrapError(err)
+}
+
+// SetLoginLockedUntil blocks the subject until lockedUntil, unless it is
+// already blocked for longer.
+func SetLoginLockedUntil(ctx context.Context, kind, subject string, lockedUntil time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := loginAttemptCollection.UpdateOne(ctx, loginAttemptFilter(kind, subject), bson.M{"$max": bson.M{"lockedUntil": lockedUntil}})
+	return wrapError(err)
+}
+
+// ClearLoginFailures removes the counter for a subject and reports whether
+// there was one.
+func ClearLoginFailures(ctx context.Context, kind, subject string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := loginAttemptCollection.DeleteOne(ctx, loginAttemptFilter(kind, subject))
+	if err != nil {
+		return false, wrapError(err)
+	}
+
+	return result.DeletedCount > 0, nil
+}
+
+// GetActiveLoginLockouts lists every username and IP that is currently
+// blocked from logging in.
+func GetActiveLoginLockouts(ctx context.Context) ([]LoginAttemptModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "lockedUntil", Value: -1}})
+	cursor, err := loginAttemptCollection.Find(ctx, bson.M{"lockedUntil": bson.M{"$gt": time.Now()}}, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	attempts := []LoginAttemptModel{}
+	if err := cursor.All(ctx, &attempts); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return attempts, nil
+}
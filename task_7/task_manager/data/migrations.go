@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"log/slog"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	defaultWorkspaceID   = "defaultWorkspace"
+	defaultWorkspaceName = "Default"
+)
+
+// unscopedTasks matches tasks created before workspaces existed, which no
+// workspace route can reach.
+var unscopedTasks = bson.M{"$or": bson.A{
+	bson.M{"workspaceId": 0},
+	bson.M{"workspaceId": bson.M{"$exists": false}},
+}}
+
+// migrateUnscopedTasks moves tasks without a workspace into a default
+// workspace. It runs at every start and does nothing once they are moved.
+func migrateUnscopedTasks(ctx context.Context) error {
+	count, err := taskCollection.CountDocuments(ctx, unscopedTasks)
+	if err != nil {
+		return wrapError(err)
+	}
+	if count == 0 {
+		return nil
+	}
+
+	workspaceID, err := ensureDefaultWorkspace(ctx)
+	if err != nil {
+		return err
+	}
+
+	result, err := taskCollection.UpdateMany(ctx, unscopedTasks, bson.M{"$set": bson.M{"workspaceId": workspaceID}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	slog.Info("moved tasks without a workspace into the default workspace", "tasks", result.ModifiedCount, "workspace_id", workspaceID)
+	return nil
+}
+
+// ensureDefaultWorkspace returns the default workspace's ID, creating it if
+// needed. Its ID is claimed in the settings collection first, so servers
+// starting together agree on one workspace. Before workspaces, any user
+// could read tasks and admins could change them, so users join as members
+// and admins as workspace admins.
+func ensureDefaultWorkspace(ctx context.Context) (int, error) {
+	nextID, err := getNextWorkspaceID(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var claim struct {
+		WorkspaceID int `bson:"workspaceId"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err = settingsCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": defaultWorkspaceID},
+		bson.M{"$setOnInsert": bson.M{"workspaceId": nextID}},
+		opts).Decode(&claim)
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	cursor, err := userCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return 0, wrapError(err)
+	}
+	var users []UserModel
+	if err := cursor.All(ctx, &users); err != nil {
+		return 0, wrapError(err)
+	}
+
+	members := make([]WorkspaceMember, 0, len(users))
+	for _, user := range users {
+		role := WorkspaceRoleMember
+		if user.Role == "admin" {
+			role = WorkspaceRoleAdmin
+		}
+		members = append(members, WorkspaceMember{UserID: user.ID, Role: role})
+	}
+
+	// The workspace may already exist if an earlier start stopped before
+	// moving the tasks.
+	_, err = workspaceCollection.UpdateOne(ctx,
+		bson.M{"id": claim.WorkspaceID},
+		bson.M{"$setOnInsert": bson.M{"name": defaultWorkspaceName, "members": members}},
+		options.Update().SetUpsert(true))
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	return claim.WorkspaceID, nil
+}
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"context"
+
+	"task_manager/metrics"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+)
+
+// commandMonitor observes every MongoDB command for both metrics and
+// tracing; a client accepts only one monitor. Spans join the request's trace
+// when the operation is given the request context.
+func commandMonitor() *event.CommandMonitor {
+	monitors := []*event.CommandMonitor{metrics.CommandMonitor(), otelmongo.NewMonitor()}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, m := range monitors {
+				m.Started(ctx, e)
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, m := range monitors {
+				m.Succeeded(ctx, e)
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, m := range monitors {
+				m.Failed(ctx, e)
+			}
+		},
+	}
+}
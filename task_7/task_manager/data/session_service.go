@@ -0,0 +1,153 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionModel records one login. Its ID is embedded in the login token, so
+// revoking the session signs that device out.
+type SessionModel struct {
+	ID         int       `json:"id" bson:"id"`
+	UserID     int       `json:"userId" bson:"userId"`
+	UserAgent  string    `json:"userAgent" bson:"userAgent"`
+	IP         string    `json:"ip" bson:"ip"`
+	CreatedAt  time.Time `json:"createdAt" bson:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt" bson:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt" bson:"expiresAt"`
+	Revoked    bool      `json:"-" bson:"revoked"`
+	// Current marks the session making the request when listing.
+	Current bool `json:"current" bson:"-"`
+}
+
+var sessionCollection *mongo.Collection
+
+// CreateSession starts a session lasting ttl; the login token issued with
+// it should expire at the same time.
+func CreateSession(ctx context.Context, userID int, userAgent, ip string, ttl time.Duration) (SessionModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	nextID, err := nextSequence(ctx, "sessions", 0)
+	if err != nil {
+		return SessionModel{}, wrapError(err)
+	}
+
+	now := time.Now()
+	session := SessionModel{
+		ID:         nextID,
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IP:         ip,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(ttl),
+	}
+
+	if _, err := sessionCollection.InsertOne(ctx, session); err != nil {
+		return SessionModel{}, wrapError(err)
+	}
+
+	return session, nil
+}
+
+// GetActiveSession returns userID's session if it is neither revoked nor
+// expired, and records that it was seen.
+func GetActiveSession(ctx context.Context, userID, id int) (SessionModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.M{
+		"id":        id,
+		"userId":    userID,
+		"revoked":   false,
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	var session SessionModel
+	err := sessionCollection.FindOne(ctx, filter).Decode(&session)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return SessionModel{}, notFound("session")
+	}
+	if err != nil {
+		return SessionModel{}, wrapError(err)
+	}
+
+	if now.Sub(session.LastSeenAt) > lastUsedResolution {
+		_, err := sessionCollection.UpdateOne(ctx, bson.M{"id": id}, bson.M{"$set": bson.M{"lastSeenAt": now}})
+		if err != nil {
+			return SessionModel{}, wrapError(err)
+		}
+		session.LastSeenAt = now
+	}
+
+	return session, nil
+}
+
+// GetActiveSessions lists userID's signed-in devices, most recently used
+// first.
+func GetActiveSessions(ctx context.Context, userID int) ([]SessionModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"userId":    userID,
+		"revoked":   false,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+	cursor, err := sessionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	sessions := []SessionModel{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return sessions, nil
+}
+
+func RevokeSession(ctx context.Context, userID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"id": id, "userId": userID, "revoked": false}
+	result, err := sessionCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("session")
+	}
+
+	return nil
+}
+
+// RevokeAllSessions signs userID out everywhere except the session exceptID,
+// which may be 0 to keep none. It returns how many sessions were revoked.
+func RevokeAllSessions(ctx context.Context, userID, exceptID int) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"userId":  userID,
+		"revoked": false,
+		"id":      bson.M{"$ne": exceptID},
+	}
+	result, err := sessionCollection.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"revoked": true}})
+	if err != nil {
+		return 0, wrapError(err)
+	}
+
+	return result.ModifiedCount, nil
+}
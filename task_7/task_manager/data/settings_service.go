@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const securityPolicyID = "security"
+
+// SecurityPolicy holds instance-wide security settings managed by admins.
+type SecurityPolicy struct {
+	RequireAdminTwoFactor bool `json:"requireAdminTwoFactor" bson:"requireAdminTwoFactor"`
+}
+
+var settingsCollection *mongo.Collection
+
+// GetSecurityPolicy returns the stored policy, or the zero policy if none has
+// been saved yet.
+func GetSecurityPolicy(ctx context.Context) (SecurityPolicy, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var policy SecurityPolicy
+	err := settingsCollection.FindOne(ctx, bson.M{"_id": securityPolicyID}).Decode(&policy)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return SecurityPolicy{}, nil
+	}
+	if err != nil {
+		return SecurityPolicy{}, wrapError(err)
+	}
+
+	return policy, nil
+}
+
+func UpdateSecurityPolicy(ctx context.Context, policy SecurityPolicy) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Update().SetUpsert(true)
+	_, err := settingsCollection.UpdateOne(ctx, bson.M{"_id": securityPolicyID}, bson.M{"$set": policy}, opts)
+	return wrapError(err)
+}
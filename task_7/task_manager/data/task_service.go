@@ -3,8 +3,6 @@ package database
 import (
 	"context"
 	"errors"
-	"log"
-	"os"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -12,152 +10,173 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// TaskModel is both the stored task and the request body for creating and
+// updating one. The binding rules check both request bodies alike; the
+// custom rules are registered by the validation package.
 type TaskModel struct {
 	ID          int    `json:"id" bson:"id"`
-	Title       string `json:"title" bson:"title"`
-	Description string `json:"description" bson:"description"`
-	DueDate     string `json:"dueDate" bson:"dueDate"`
+	WorkspaceID int    `json:"workspaceId" bson:"workspaceId"`
+	OwnerID     int    `json:"ownerId" bson:"ownerId"`
+	Title       string `json:"title" bson:"title" binding:"required,notblank,max=200"`
+	Description string `json:"description" bson:"description" binding:"max=2000"`
+	DueDate     string `json:"dueDate" bson:"dueDate" binding:"omitempty,rfc3339,recent"`
+	Priority    string `json:"priority" bson:"priority" binding:"omitempty,oneof=low medium high"`
 	Status      bool   `json:"status" bson:"status"`
 }
 
 var taskCollection *mongo.Collection
 
-func init() {
-	mongoURL := os.Getenv("MONGO_URL")
-	if mongoURL == "" {
-		mongoURL = "mongodb://localhost:27017"
-	}
-
-	log.Printf("MongoDB connection URL: %s", mongoURL)
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	clientOpts := options.Client().ApplyURI(mongoURL)
-	client, err := mongo.Connect(ctx, clientOpts)
-	if err != nil {
-		log.Fatalf("failed to connect to MongoDB: %v", err)
-	}
-
-	if err := client.Ping(ctx, nil); err != nil {
-		log.Fatalf("failed to ping MongoDB: %v", err)
-	}
-
-	db := client.Database("task_manager_db")
-	taskCollection = db.Collection("tasks")
-}
-
+// getNextTaskID hands out IDs from a counter, so concurrent creates never
+// get the same one.
 func getNextTaskID(ctx context.Context) (int, error) {
 	var last TaskModel
 	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
 	err := taskCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
-	if errors.Is(err, mongo.ErrNoDocuments) {
-		return 1, nil
-	}
-	if err != nil {
-		return 0, err
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, wrapError(err)
 	}
-	return last.ID + 1, nil
+	return nextSequence(ctx, "tasks", last.ID)
 }
 
-func GetAllTasks() []TaskModel {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func GetAllTasks(ctx context.Context, workspaceID int) ([]TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cursor, err := taskCollection.Find(ctx, bson.D{})
+	cursor, err := taskCollection.Find(ctx, bson.M{"workspaceId": workspaceID})
 	if err != nil {
-		log.Printf("error retrieving tasks from MongoDB: %v", err)
-		return []TaskModel{}
+		return nil, wrapError(err)
 	}
 	defer cursor.Close(ctx)
 
-	var tasks []TaskModel
-	for cursor.Next(ctx) {
-		var task TaskModel
-		if err := cursor.Decode(&task); err != nil {
-			log.Printf("error decoding task document: %v", err)
-			continue
-		}
-		tasks = append(tasks, task)
-	}
-
-	if err := cursor.Err(); err != nil {
-		log.Printf("cursor error while reading tasks: %v", err)
+	tasks := []TaskModel{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, wrapError(err)
 	}
 
-	return tasks
+	return tasks, nil
 }
 
-func GetTaskByID(id int) (TaskModel, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func GetTaskByID(ctx context.Context, workspaceID, id int) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var task TaskModel
-	err := taskCollection.FindOne(ctx, bson.M{"id": id}).Decode(&task)
+	err := taskCollection.FindOne(ctx, bson.M{"workspaceId": workspaceID, "id": id}).Decode(&task)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return TaskModel{}, false
+		return TaskModel{}, notFound("task")
 	}
 	if err != nil {
-		log.Printf("error fetching task with id=%d: %v", id, err)
-		return TaskModel{}, false
+		return TaskModel{}, wrapError(err)
 	}
 
-	return task, true
+	return task, nil
 }
 
-func CreateTask(newTask TaskModel) TaskModel {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func CreateTask(ctx context.Context, workspaceID, ownerID int, newTask TaskModel) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	nextID, err := getNextTaskID(ctx)
 	if err != nil {
-		log.Printf("error generating next task ID: %v", err)
-		return TaskModel{}
+		return TaskModel{}, err
 	}
 	newTask.ID = nextID
+	newTask.WorkspaceID = workspaceID
+	newTask.OwnerID = ownerID
 
 	_, err = taskCollection.InsertOne(ctx, newTask)
 	if err != nil {
-		log.Printf("error inserting task into MongoDB: %v", err)
-		return TaskModel{}
+		return TaskModel{}, wrapError(err)
 	}
 
-	return newTask
+	return newTask, nil
 }
 
-func UpdateTask(id int, updatedDetails TaskModel) (TaskModel, bool) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func UpdateTask(ctx context.Context, workspaceID, id int, updatedDetails TaskModel) (TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	updatedDetails.ID = id
-
-	filter := bson.M{"id": id}
-	update := bson.M{"$set": updatedDetails}
+	filter := bson.M{"workspaceId": workspaceID, "id": id}
+	update := bson.M{"$set": bson.M{
+		"title":       updatedDetails.Title,
+		"description": updatedDetails.Description,
+		"dueDate":     updatedDetails.DueDate,
+		"priority":    updatedDetails.Priority,
+		"status":      updatedDetails.Status,
+	}}
 
 	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
 	var updated TaskModel
 	err := taskCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&updated)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return TaskModel{}, false
+		return TaskModel{}, notFound("task")
+	}
+	if err != nil {
+		return TaskModel{}, wrapError(err)
+	}
+
+	return updated, nil
+}
+
+func DeleteTask(ctx context.Context, workspaceID, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := taskCollection.DeleteOne(ctx, bson.M{"workspaceId": workspaceID, "id": id})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return notFound("task")
+	}
+
+	return nil
+}
+
+// CountTasksByStatus counts tasks across all workspaces, for metrics.
+func CountTasksByStatus(ctx context.Context) (open, done int64, err error) {
+	done, err = taskCollection.CountDocuments(ctx, bson.M{"status": true})
+	if err != nil {
+		return 0, 0, wrapError(err)
 	}
+
+	open, err = taskCollection.CountDocuments(ctx, bson.M{"status": bson.M{"$ne": true}})
 	if err != nil {
-		log.Printf("error updating task with id=%d: %v", id, err)
-		return TaskModel{}, false
+		return 0, 0, wrapError(err)
 	}
 
-	return updated, true
+	return open, done, nil
 }
 
-func DeleteTask(id int) bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// ReassignTasks hands every task owned by fromUserID over to toUserID and
+// returns how many tasks moved.
+func ReassignTasks(ctx context.Context, fromUserID, toUserID int) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	result, err := taskCollection.DeleteOne(ctx, bson.M{"id": id})
+	result, err := taskCollection.UpdateMany(ctx, bson.M{"ownerId": fromUserID}, bson.M{"$set": bson.M{"ownerId": toUserID}})
 	if err != nil {
-		log.Printf("error deleting task with id=%d: %v", id, err)
-		return false
+		return 0, wrapError(err)
 	}
 
-	return result.DeletedCount > 0
+	return result.ModifiedCount, nil
 }
 
+func GetTasksByOwner(ctx context.Context, ownerID int) ([]TaskModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := taskCollection.Find(ctx, bson.M{"ownerId": ownerID})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	tasks := []TaskModel{}
+	if err := cursor.All(ctx, &tasks); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return tasks, nil
+}
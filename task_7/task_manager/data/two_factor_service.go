@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const recoveryCodeCount = 10
+
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+
+	for i := range codes {
+		raw := make([]byte, 7)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, nil, wrapError(err)
+		}
+		code := strings.ToLower(encoding.EncodeToString(raw))[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+func SetPendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := userCollection.UpdateOne(ctx, bson.M{"id": userID}, bson.M{"$set": bson.M{"pendingTotpSecret": secret}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
+// EnableTOTP promotes the pending secret to the active one, records the step
+// of the confirming code and returns a fresh set of recovery codes. The codes
+// are only stored hashed, so this is the one time they can be shown.
+func EnableTOTP(ctx context.Context, userID int, secret string, step int64) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	filter := bson.M{"id": userID, "pendingTotpSecret": secret}
+	update := bson.M{
+		"$set": bson.M{
+			"twoFactorEnabled": true,
+			"totpSecret":       secret,
+			"totpLastStep":     step,
+			"recoveryCodes":    hashes,
+		},
+		"$unset": bson.M{"pendingTotpSecret": ""},
+	}
+
+	result, err := userCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return nil, conflict("no_pending_enrollment", "no pending enrollment")
+	}
+
+	return codes, nil
+}
+
+func DisableTOTP(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	update := bson.M{
+		"$set":   bson.M{"twoFactorEnabled": false, "totpLastStep": 0},
+		"$unset": bson.M{"totpSecret": "", "pendingTotpSecret": "", "recoveryCodes": ""},
+	}
+
+	_, err := userCollection.UpdateOne(ctx, bson.M{"id": userID}, update)
+	return wrapError(err)
+}
+
+// RecordTOTPStep accepts a verified code's time step only if it is newer
+// than the last accepted one, making each code single-use.
+func RecordTOTPStep(ctx context.Context, userID int, step int64) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"id": userID, "totpLastStep": bson.M{"$lt": step}}
+	result, err := userCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"totpLastStep": step}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return conflict("code_already_used", "code already used")
+	}
+
+	return nil
+}
+
+func ConsumeRecoveryCode(ctx context.Context, userID int, code string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	hash := hashRecoveryCode(code)
+	filter := bson.M{"id": userID, "recoveryCodes": hash}
+	result, err := userCollection.UpdateOne(ctx, filter, bson.M{"$pull": bson.M{"recoveryCodes": hash}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return &Error{Kind: ErrNotFound, Code: "invalid_code", Message: "invalid recovery code"}
+	}
+
+	return nil
+}
@@ -3,146 +3,235 @@ package database
 import (
 	"context"
 	"errors"
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
 	"sync"
 	"time"
 
-	"golang.org/x/crypto/bcrypt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type UserModel struct {
-	ID       int    `json:"id" bson:"id"`
-	Username string `json:"username" bson:"username"`
-	Password string `json:"-" bson:"password"`
-	Role     string `json:"role" bson:"role"`
+	ID          int    `json:"id" bson:"id"`
+	Username    string `json:"username" bson:"username"`
+	DisplayName string `json:"displayName,omitempty" bson:"displayName,omitempty"`
+	Email       string `json:"email,omitempty" bson:"email,omitempty"`
+	// EmailVerified is reset whenever Email changes.
+	EmailVerified bool   `json:"emailVerified" bson:"emailVerified"`
+	Timezone      string `json:"timezone,omitempty" bson:"timezone,omitempty"`
+	Password      string `json:"-" bson:"password"`
+	Role          string `json:"role" bson:"role"`
+	Disabled      bool   `json:"disabled" bson:"disabled"`
+	// ServiceAccount users have no password and authenticate only with
+	// API tokens.
+	ServiceAccount bool `json:"serviceAccount" bson:"serviceAccount"`
+	// ExternalIdentities are the identity provider accounts that can sign
+	// in as this user through SSO.
+	ExternalIdentities []ExternalIdentity `json:"-" bson:"externalIdentities,omitempty"`
+	// TokenVersion is embedded in issued tokens; bumping it invalidates
+	// every token issued before.
+	TokenVersion int `json:"-" bson:"tokenVersion"`
+
+	TwoFactorEnabled bool   `json:"twoFactorEnabled" bson:"twoFactorEnabled"`
+	TOTPSecret       string `json:"-" bson:"totpSecret,omitempty"`
+	// PendingTOTPSecret holds a secret between enroll and confirm.
+	PendingTOTPSecret string `json:"-" bson:"pendingTotpSecret,omitempty"`
+	// TOTPLastStep is the last accepted time step, to reject replayed codes.
+	TOTPLastStep  int64    `json:"-" bson:"totpLastStep"`
+	RecoveryCodes []string `json:"-" bson:"recoveryCodes,omitempty"`
 }
 
-var (
-	userCollection *mongo.Collection
-	userInitOnce   sync.Once
-)
-
-func initUsers() {
-	userInitOnce.Do(func() {
-		mongoURL := os.Getenv("MONGO_URL")
-		if mongoURL == "" {
-			mongoURL = "mongodb://localhost:27017"
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		defer cancel()
-
-		clientOpts := options.Client().ApplyURI(mongoURL)
-		client, err := mongo.Connect(ctx, clientOpts)
-		if err != nil {
-			log.Fatalf("failed to connect to MongoDB: %v", err)
-		}
-
-		if err := client.Ping(ctx, nil); err != nil {
-			log.Fatalf("failed to ping MongoDB: %v", err)
-		}
-
-		db := client.Database("task_manager_db")
-		userCollection = db.Collection("users")
-	})
+type ExternalIdentity struct {
+	Issuer  string `bson:"issuer"`
+	Subject string `bson:"subject"`
 }
 
+var userCollection *mongo.Collection
+
+// getNextUserID never reuses the ID of a deleted user, since tokens issued
+// to that user reference the ID.
 func getNextUserID(ctx context.Context) (int, error) {
 	var last UserModel
 	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
 	err := userCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
-	if errors.Is(err, mongo.ErrNoDocuments) {
-		return 1, nil
-	}
-	if err != nil {
-		return 0, err
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, wrapError(err)
 	}
-	return last.ID + 1, nil
+	return nextSequence(ctx, "users", last.ID)
 }
 
-func isDatabaseEmpty(ctx context.Context) bool {
+func isDatabaseEmpty(ctx context.Context) (bool, error) {
 	count, err := userCollection.CountDocuments(ctx, bson.D{})
 	if err != nil {
-		log.Printf("error counting users: %v", err)
-		return false
+		return false, wrapError(err)
 	}
-	return count == 0
+	return count == 0, nil
 }
 
-func CreateUser(username, password string) (UserModel, error) {
-	initUsers()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+// firstUserID is the settings document naming the account that became admin
+// because it was the first. Inserting it can only succeed once, so two
+// registrations on an empty database cannot both become admin.
+const firstUserID = "firstUser"
+
+// claimFirstUser reports whether userID is the first account.
+func claimFirstUser(ctx context.Context, userID int) (bool, error) {
+	empty, err := isDatabaseEmpty(ctx)
+	if err != nil || !empty {
+		return false, err
+	}
 
-	existingUser, _ := GetUserByUsername(username)
-	if existingUser.Username != "" {
-		return UserModel{}, errors.New("username already exists")
+	_, err = settingsCollection.InsertOne(ctx, bson.M{"_id": firstUserID, "userId": userID})
+	if mongo.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return true, nil
+}
+
+// releaseFirstUser gives up the claim of an account that could not be
+// inserted, so the next registration becomes admin instead.
+func releaseFirstUser(ctx context.Context, userID int) {
+	_, err := settingsCollection.DeleteOne(ctx, bson.M{"_id": firstUserID, "userId": userID})
+	if err != nil {
+		slog.ErrorContext(ctx, "error releasing first user claim", "user_id", userID, "error", err)
 	}
+}
+
+func CreateUser(ctx context.Context, username, password, email string) (UserModel, error) {
+	return createUser(ctx, username, password, email, "", false)
+}
 
+// CreateInvitedUser creates an account for someone accepting an invitation,
+// with the email and instance role the invitation was issued for. The email
+// counts as verified since the invitee proved they received the invitation.
+func CreateInvitedUser(ctx context.Context, username, password, email, role string) (UserModel, error) {
+	return createUser(ctx, username, password, email, role, true)
+}
+
+// createUser hashes the password and inserts a new user. An empty role means
+// "user", except for the very first account which always becomes admin.
+func createUser(ctx context.Context, username, password, email, role string, emailVerified bool) (UserModel, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		return UserModel{}, err
 	}
 
+	return insertUser(ctx, UserModel{
+		Username:      username,
+		Email:         email,
+		EmailVerified: emailVerified,
+		Password:      string(hashedPassword),
+		Role:          role,
+	})
+}
+
+// CreateServiceAccount creates a password-less account for automation. It
+// can only authenticate with API tokens issued by an admin.
+func CreateServiceAccount(ctx context.Context, name, role string) (UserModel, error) {
+	return insertUser(ctx, UserModel{
+		Username:       name,
+		Role:           role,
+		ServiceAccount: true,
+	})
+}
+
+func insertUser(ctx context.Context, user UserModel) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := GetUserByUsername(ctx, user.Username)
+	if err == nil {
+		return UserModel{}, conflict("username_taken", "username already exists")
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return UserModel{}, err
+	}
+
 	nextID, err := getNextUserID(ctx)
 	if err != nil {
 		return UserModel{}, err
 	}
 
-	role := "user"
-	if isDatabaseEmpty(ctx) {
-		role = "admin"
+	user.ID = nextID
+	if user.Role == "" {
+		user.Role = "user"
 	}
-
-	user := UserModel{
-		ID:       nextID,
-		Username: username,
-		Password: string(hashedPassword),
-		Role:     role,
+	first := false
+	if !user.ServiceAccount {
+		if first, err = claimFirstUser(ctx, user.ID); err != nil {
+			return UserModel{}, err
+		}
+	}
+	if first {
+		user.Role = "admin"
 	}
 
+	// The unique index on username settles registrations racing past the
+	// check above.
 	_, err = userCollection.InsertOne(ctx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		if _, lookupErr := GetUserByUsername(ctx, user.Username); lookupErr == nil {
+			err = conflict("username_taken", "username already exists")
+		}
+	}
 	if err != nil {
-		return UserModel{}, err
+		if first {
+			releaseFirstUser(ctx, user.ID)
+		}
+		return UserModel{}, wrapError(err)
 	}
 
 	user.Password = ""
 	return user, nil
 }
 
-func GetUserByUsername(username string) (UserModel, error) {
-	initUsers()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// HasUsers reports whether at least one account exists. Open registration is
+// always allowed until it does, so a fresh instance can be bootstrapped.
+func HasUsers(ctx context.Context) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	count, err := userCollection.CountDocuments(ctx, bson.D{}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, wrapError(err)
+	}
+	return count > 0, nil
+}
+
+func GetUserByUsername(ctx context.Context, username string) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user UserModel
 	err := userCollection.FindOne(ctx, bson.M{"username": username}).Decode(&user)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return UserModel{}, errors.New("user not found")
+		return UserModel{}, notFound("user")
 	}
 	if err != nil {
-		return UserModel{}, err
+		return UserModel{}, wrapError(err)
 	}
 
 	return user, nil
 }
 
-func GetUserByID(id int) (UserModel, error) {
-	initUsers()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+func GetUserByID(ctx context.Context, id int) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	var user UserModel
 	err := userCollection.FindOne(ctx, bson.M{"id": id}).Decode(&user)
 	if errors.Is(err, mongo.ErrNoDocuments) {
-		return UserModel{}, errors.New("user not found")
+		return UserModel{}, notFound("user")
 	}
 	if err != nil {
-		return UserModel{}, err
+		return UserModel{}, wrapError(err)
 	}
 
 	return user, nil
@@ -153,9 +242,23 @@ func VerifyPassword(hashedPassword, password string) bool {
 	return err == nil
 }
 
-func PromoteUser(username string) error {
-	initUsers()
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+var (
+	dummyPasswordHash     []byte
+	dummyPasswordHashOnce sync.Once
+)
+
+// SimulatePasswordCheck spends the same time as VerifyPassword on a real
+// account. Login calls it for unknown usernames so response times do not
+// reveal which usernames exist.
+func SimulatePasswordCheck(password string) {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	})
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
+
+func PromoteUser(ctx context.Context, username string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
 	filter := bson.M{"username": username}
@@ -163,13 +266,320 @@ func PromoteUser(username string) error {
 
 	result, err := userCollection.UpdateOne(ctx, filter, update)
 	if err != nil {
-		return err
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
+// ListUsers returns one page of users whose username or email contains
+// search (case-insensitive), ordered by ID, along with the total match count.
+func ListUsers(ctx context.Context, search string, page, limit int) ([]UserModel, int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if search != "" {
+		pattern := bson.M{"$regex": regexp.QuoteMeta(search), "$options": "i"}
+		filter["$or"] = bson.A{bson.M{"username": pattern}, bson.M{"email": pattern}}
+	}
+
+	total, err := userCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "id", Value: 1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := userCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, 0, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []UserModel{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, 0, wrapError(err)
+	}
+
+	return users, total, nil
+}
+
+// CountActiveAdmins counts admins that are not disabled, i.e. the accounts
+// that can still administer the instance.
+func CountActiveAdmins(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	count, err := userCollection.CountDocuments(ctx, bson.M{"role": "admin", "disabled": bson.M{"$ne": true}})
+	return count, wrapError(err)
+}
+
+func (u UserModel) activeAdmin() bool {
+	return u.Role == "admin" && !u.Disabled
+}
+
+// UpdateUser sets the role and disabled flag of an account. It refuses with
+// the conflict "last_admin" to demote or disable the last active admin.
+// MongoDB cannot make the update conditional on other documents, so the
+// update is checked after it is written and undone if no active admin is
+// left; two admins removed at the same time then both back out rather than
+// both succeeding.
+func UpdateUser(ctx context.Context, id int, role *string, disabled *bool) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	set := bson.M{}
+	if role != nil {
+		set["role"] = *role
+	}
+	if disabled != nil {
+		set["disabled"] = *disabled
+	}
+	if len(set) == 0 {
+		return GetUserByID(ctx, id)
+	}
+
+	var before UserModel
+	err := userCollection.FindOneAndUpdate(ctx, bson.M{"id": id}, bson.M{"$set": set}).Decode(&before)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserModel{}, notFound("user")
+	}
+	if err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	updated := before
+	if role != nil {
+		updated.Role = *role
+	}
+	if disabled != nil {
+		updated.Disabled = *disabled
+	}
+
+	if before.activeAdmin() && !updated.activeAdmin() {
+		admins, err := CountActiveAdmins(ctx)
+		if err != nil || admins == 0 {
+			restore := bson.M{"$set": bson.M{"role": before.Role, "disabled": before.Disabled}}
+			if _, undoErr := userCollection.UpdateOne(ctx, bson.M{"id": id}, restore); undoErr != nil {
+				return UserModel{}, wrapError(undoErr)
+			}
+			if err != nil {
+				return UserModel{}, err
+			}
+			return UserModel{}, conflict("last_admin", "cannot remove the last admin")
+		}
+	}
+
+	return updated, nil
+}
+
+func DeleteUser(ctx context.Context, id int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := userCollection.DeleteOne(ctx, bson.M{"id": id})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.DeletedCount == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
+func UpdateProfile(ctx context.Context, id int, displayName, email, timezone *string) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	set := bson.M{}
+	if displayName != nil {
+		set["displayName"] = *displayName
+	}
+	if email != nil {
+		set["email"] = *email
+		set["emailVerified"] = false
+	}
+	if timezone != nil {
+		set["timezone"] = *timezone
+	}
+	if len(set) == 0 {
+		return GetUserByID(ctx, id)
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated UserModel
+	err := userCollection.FindOneAndUpdate(ctx, bson.M{"id": id}, bson.M{"$set": set}, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserModel{}, notFound("user")
+	}
+	if err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	return updated, nil
+}
+
+// ChangePassword stores a new password hash and bumps the token version so
+// that tokens issued with the old password stop working. It returns the
+// updated user so the caller can be issued a fresh token.
+func ChangePassword(ctx context.Context, id int, newPassword string) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return UserModel{}, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{"password": string(hashedPassword)},
+		"$inc": bson.M{"tokenVersion": 1},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var updated UserModel
+	err = userCollection.FindOneAndUpdate(ctx, bson.M{"id": id}, update, opts).Decode(&updated)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserModel{}, notFound("user")
+	}
+	if err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	return updated, nil
+}
+
+// GetUserByVerifiedEmail finds the account that has verified ownership of
+// email. Unverified addresses are ignored so they cannot receive reset links.
+func GetUserByVerifiedEmail(ctx context.Context, email string) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user UserModel
+	err := userCollection.FindOne(ctx, bson.M{"email": email, "emailVerified": true}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserModel{}, notFound("user")
+	}
+	if err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	return user, nil
+}
+
+// MarkEmailVerified verifies email for userID, provided it is still the
+// address on the account.
+func MarkEmailVerified(ctx context.Context, userID int, email string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"id": userID, "email": email}
+	result, err := userCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"emailVerified": true}})
+	if err != nil {
+		return wrapError(err)
 	}
 
 	if result.MatchedCount == 0 {
-		return errors.New("user not found")
+		return conflict("email_changed", "email has changed")
 	}
 
 	return nil
 }
 
+func GetServiceAccounts(ctx context.Context) ([]UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "id", Value: 1}})
+	cursor, err := userCollection.Find(ctx, bson.M{"serviceAccount": true}, opts)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []UserModel{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return users, nil
+}
+
+func GetUserByExternalIdentity(ctx context.Context, issuer, subject string) (UserModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The dotted filter also matches an issuer and a subject from two
+	// different identities, so the pair is checked on each candidate.
+	// $elemMatch would not need that, but MongoDB-compatible servers such as
+	// FerretDB do not all support it on embedded documents.
+	filter := bson.M{"externalIdentities.issuer": issuer, "externalIdentities.subject": subject}
+	cursor, err := userCollection.Find(ctx, filter)
+	if err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	var users []UserModel
+	if err := cursor.All(ctx, &users); err != nil {
+		return UserModel{}, wrapError(err)
+	}
+
+	want := ExternalIdentity{Issuer: issuer, Subject: subject}
+	for _, user := range users {
+		if slices.Contains(user.ExternalIdentities, want) {
+			return user, nil
+		}
+	}
+
+	return UserModel{}, notFound("user")
+}
+
+func LinkExternalIdentity(ctx context.Context, userID int, identity ExternalIdentity) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := userCollection.UpdateOne(ctx, bson.M{"id": userID}, bson.M{"$addToSet": bson.M{"externalIdentities": identity}})
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("user")
+	}
+
+	return nil
+}
+
+// CreateExternalUser provisions a password-less account for an SSO login.
+// If username is taken, a numeric suffix is added until a free one is found.
+func CreateExternalUser(ctx context.Context, username, email string, emailVerified bool, role string, identity ExternalIdentity) (UserModel, error) {
+	user := UserModel{
+		Email:              email,
+		EmailVerified:      emailVerified,
+		Role:               role,
+		ExternalIdentities: []ExternalIdentity{identity},
+	}
+
+	for attempt := 1; attempt <= 20; attempt++ {
+		user.Username = username
+		if attempt > 1 {
+			user.Username = fmt.Sprintf("%s-%d", username, attempt)
+		}
+
+		created, err := insertUser(ctx, user)
+		if !errors.Is(err, ErrConflict) {
+			return created, err
+		}
+	}
+
+	return UserModel{}, conflict("username_taken", "username already exists")
+}
@@ -0,0 +1,123 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeEmailVerification = "email_verification"
+)
+
+// UserTokenModel is a single-use secret mailed to a user. Only the SHA-256
+// of the token is stored, so a database leak does not expose usable links.
+type UserTokenModel struct {
+	TokenHash string    `bson:"tokenHash"`
+	UserID    int       `bson:"userId"`
+	Purpose   string    `bson:"purpose"`
+	Email     string    `bson:"email,omitempty"`
+	CreatedAt time.Time `bson:"createdAt"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	Used      bool      `bson:"used"`
+}
+
+var userTokenCollection *mongo.Collection
+
+func hashUserToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateUserToken stores a new token for userID and returns the raw value to
+// send to the user. Older unused tokens for the same purpose are invalidated.
+func CreateUserToken(ctx context.Context, userID int, purpose, email string, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", wrapError(err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+
+	_, err := userTokenCollection.UpdateMany(ctx,
+		bson.M{"userId": userID, "purpose": purpose, "used": false},
+		bson.M{"$set": bson.M{"used": true}})
+	if err != nil {
+		return "", wrapError(err)
+	}
+
+	now := time.Now()
+	_, err = userTokenCollection.InsertOne(ctx, UserTokenModel{
+		TokenHash: hashUserToken(token),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	})
+	if err != nil {
+		return "", wrapError(err)
+	}
+
+	return token, nil
+}
+
+// GetUserToken looks up a valid, unexpired token without using it up.
+func GetUserToken(ctx context.Context, token, purpose string) (UserTokenModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"tokenHash": hashUserToken(token),
+		"purpose":   purpose,
+		"used":      false,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	var found UserTokenModel
+	err := userTokenCollection.FindOne(ctx, filter).Decode(&found)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserTokenModel{}, &Error{Kind: ErrNotFound, Code: "invalid_token", Message: "invalid or expired token"}
+	}
+	if err != nil {
+		return UserTokenModel{}, wrapError(err)
+	}
+
+	return found, nil
+}
+
+// ConsumeUserToken atomically marks a valid, unexpired token as used and
+// returns it. Any second attempt with the same token fails.
+func ConsumeUserToken(ctx context.Context, token, purpose string) (UserTokenModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{
+		"tokenHash": hashUserToken(token),
+		"purpose":   purpose,
+		"used":      false,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+	update := bson.M{"$set": bson.M{"used": true}}
+
+	var consumed UserTokenModel
+	err := userTokenCollection.FindOneAndUpdate(ctx, filter, update).Decode(&consumed)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return UserTokenModel{}, &Error{Kind: ErrNotFound, Code: "invalid_token", Message: "invalid or expired token"}
+	}
+	if err != nil {
+		return UserTokenModel{}, wrapError(err)
+	}
+
+	return consumed, nil
+}
@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	WorkspaceRoleAdmin  = "admin"
+	WorkspaceRoleMember = "member"
+)
+
+type WorkspaceMember struct {
+	UserID int    `json:"userId" bson:"userId"`
+	Role   string `json:"role" bson:"role"`
+}
+
+type WorkspaceModel struct {
+	ID      int               `json:"id" bson:"id"`
+	Name    string            `json:"name" bson:"name"`
+	Members []WorkspaceMember `json:"members" bson:"members"`
+}
+
+var workspaceCollection *mongo.Collection
+
+func getNextWorkspaceID(ctx context.Context) (int, error) {
+	var last WorkspaceModel
+	opts := options.FindOne().SetSort(bson.D{{Key: "id", Value: -1}})
+	err := workspaceCollection.FindOne(ctx, bson.D{}, opts).Decode(&last)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, wrapError(err)
+	}
+	return nextSequence(ctx, "workspaces", last.ID)
+}
+
+func CreateWorkspace(ctx context.Context, name string, ownerID int) (WorkspaceModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	nextID, err := getNextWorkspaceID(ctx)
+	if err != nil {
+		return WorkspaceModel{}, wrapError(err)
+	}
+
+	workspace := WorkspaceModel{
+		ID:   nextID,
+		Name: name,
+		Members: []WorkspaceMember{
+			{UserID: ownerID, Role: WorkspaceRoleAdmin},
+		},
+	}
+
+	_, err = workspaceCollection.InsertOne(ctx, workspace)
+	if err != nil {
+		return WorkspaceModel{}, wrapError(err)
+	}
+
+	return workspace, nil
+}
+
+func GetWorkspaceByID(ctx context.Context, id int) (WorkspaceModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var workspace WorkspaceModel
+	err := workspaceCollection.FindOne(ctx, bson.M{"id": id}).Decode(&workspace)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return WorkspaceModel{}, notFound("workspace")
+	}
+	if err != nil {
+		return WorkspaceModel{}, wrapError(err)
+	}
+
+	return workspace, nil
+}
+
+func GetWorkspacesForUser(ctx context.Context, userID int) ([]WorkspaceModel, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := workspaceCollection.Find(ctx, bson.M{"members.userId": userID})
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	defer cursor.Close(ctx)
+
+	workspaces := []WorkspaceModel{}
+	if err := cursor.All(ctx, &workspaces); err != nil {
+		return nil, wrapError(err)
+	}
+
+	return workspaces, nil
+}
+
+// GetWorkspaceRole returns the role userID holds in the workspace, or an
+// error if the workspace does not exist or the user is not a member of it.
+func GetWorkspaceRole(ctx context.Context, workspaceID, userID int) (string, error) {
+	workspace, err := GetWorkspaceByID(ctx, workspaceID)
+	if err != nil {
+		return "", err
+	}
+
+	for _, member := range workspace.Members {
+		if member.UserID == userID {
+			return member.Role, nil
+		}
+	}
+
+	return "", notFound("workspace member")
+}
+
+func AddWorkspaceMember(ctx context.Context, workspaceID, userID int, role string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{"id": workspaceID, "members.userId": bson.M{"$ne": userID}}
+	update := bson.M{"$push": bson.M{"members": WorkspaceMember{UserID: userID, Role: role}}}
+
+	result, err := workspaceCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		if _, err := GetWorkspaceByID(ctx, workspaceID); err != nil {
+			return err
+		}
+		return conflict("already_workspace_member", "user is already a workspace member")
+	}
+
+	return nil
+}
+
+// RemoveWorkspaceMember drops userID from the workspace. Like UpdateUser
+// for instance admins, it checks after the write that the workspace still
+// has an admin, and otherwise puts the member back and returns the conflict
+// "last_workspace_admin".
+func RemoveWorkspaceMember(ctx context.Context, workspaceID, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	role, err := GetWorkspaceRole(ctx, workspaceID, userID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"id": workspaceID, "members.userId": userID}
+	update := bson.M{"$pull": bson.M{"members": bson.M{"userId": userID}}}
+
+	result, err := workspaceCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return wrapError(err)
+	}
+
+	if result.MatchedCount == 0 {
+		return notFound("workspace member")
+	}
+
+	after, err := GetWorkspaceByID(ctx, workspaceID)
+	if err == nil && hasAdmin(after) {
+		return nil
+	}
+	if undoErr := AddWorkspaceMember(ctx, workspaceID, userID, role); undoErr != nil {
+		return undoErr
+	}
+	if err != nil {
+		return err
+	}
+	return conflict("last_workspace_admin", "cannot remove the last workspace admin")
+}
+
+func hasAdmin(workspace WorkspaceModel) bool {
+	for _, member := range workspace.Members {
+		if member.Role == WorkspaceRoleAdmin {
+			return true
+		}
+	}
+	return false
+}
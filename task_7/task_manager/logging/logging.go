@@ -0,0 +1,122 @@
+// Package logging configures log/slog for the server: JSON or text output,
+// a minimum level, the request ID and trace context from the context on
+// every record, and redaction of secrets.
+//
+// init applies LOG_LEVEL and LOG_FORMAT so that anything logged before the
+// configuration is loaded already uses the right format; main then calls
+// Setup with the configured values.
+// The standard log package is routed through the same handler.
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type contextKey struct{}
+
+func init() {
+	Setup(os.Getenv("LOG_LEVEL"), os.Getenv("LOG_FORMAT"))
+}
+
+// Setup installs the default logger. level is debug, info, warn or error
+// and defaults to info; format is json (the default) or text.
+func Setup(level, format string) {
+	var minLevel slog.Level
+	if err := minLevel.UnmarshalText([]byte(level)); err != nil {
+		minLevel = slog.LevelInfo
+	}
+
+	opts := &slog.HandlerOptions{Level: minLevel, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	if strings.EqualFold(format, "text") {
+		handler = slog.NewTextHandler(os.Stderr, opts)
+	} else {
+		handler = slog.NewJSONHandler(os.Stderr, opts)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+}
+
+// WithRequestID returns a copy of ctx carrying id, which is then added to
+// every record logged with that context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute names whose values are never logged.
+var sensitiveKeys = map[string]bool{
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"password":      true,
+	"secret":        true,
+	"client_secret": true,
+	"token":         true,
+	"mongo_url":     true,
+}
+
+var (
+	urlCredentials = regexp.MustCompile(`([a-zA-Z][a-zA-Z0-9+.-]*://[^:/@\s]+):[^@\s]*@`)
+	bearerToken    = regexp.MustCompile(`(?i)(bearer\s+)\S+`)
+	apiToken       = regexp.MustCompile(`\btm_[A-Za-z0-9_-]{16,}`)
+)
+
+// Redact masks credentials embedded in s: passwords in connection strings
+// and URLs, bearer tokens and API tokens.
+func Redact(s string) string {
+	s = urlCredentials.ReplaceAllString(s, "$1:"+redacted+"@")
+	s = bearerToken.ReplaceAllString(s, "${1}"+redacted)
+	return apiToken.ReplaceAllString(s, redacted)
+}
+
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if sensitiveKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, Redact(err.Error()))
+		}
+	}
+	return a
+}
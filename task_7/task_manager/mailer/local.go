@@ -0,0 +1,55 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileMailer writes each message to its own .eml file, which makes it easy
+// to pick up reset and verification links in local runs and tests.
+type FileMailer struct {
+	dir  string
+	from string
+
+	mu  sync.Mutex
+	seq int
+}
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+func (m *FileMailer) Send(msg Message) error {
+	m.mu.Lock()
+	m.seq++
+	name := fmt.Sprintf("%d-%03d.eml", time.Now().UnixNano(), m.seq)
+	m.mu.Unlock()
+
+	return os.WriteFile(filepath.Join(m.dir, name), format(m.from, msg), 0o600)
+}
+
+type ConsoleMailer struct {
+	w    io.Writer
+	from string
+
+	mu sync.Mutex
+}
+
+func NewConsoleMailer(w io.Writer, from string) *ConsoleMailer {
+	return &ConsoleMailer{w: w, from: from}
+}
+
+func (m *ConsoleMailer) Send(msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, err := fmt.Fprintf(m.w, "----- email -----\n%s----- end email -----\n", format(m.from, msg))
+	return err
+}
@@ -0,0 +1,53 @@
+package mailer
+
+import (
+	"fmt"
+	"os"
+
+	"task_manager/config"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain-text emails. Controllers depend only on this
+// interface so the SMTP implementation can be swapped for the file or
+// console one in local development.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New builds the mailer selected by cfg.Mailer:
+//
+//	smtp    - SMTPMailer using the SMTP host, port and credentials
+//	file    - FileMailer writing one file per message into cfg.Dir
+//	console - ConsoleMailer printing messages to stdout
+//
+// cfg.From sets the sender address for every implementation.
+func New(cfg config.Mail) (Mailer, error) {
+	switch cfg.Mailer {
+	case "console":
+		return NewConsoleMailer(os.Stdout, cfg.From), nil
+	case "file":
+		return NewFileMailer(cfg.Dir, cfg.From)
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, fmt.Errorf("an SMTP host is required for the smtp mailer")
+		}
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer %q", cfg.Mailer)
+	}
+}
+
+func format(from string, msg Message) []byte {
+	return []byte("From: " + from + "\r\n" +
+		"To: " + msg.To + "\r\n" +
+		"Subject: " + msg.Subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" +
+		msg.Body + "\r\n")
+}
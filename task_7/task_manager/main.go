@@ -1,16 +1,114 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"task_manager/config"
+	database "task_manager/data"
+	"task_manager/logging"
 	"task_manager/router"
+	"task_manager/tracing"
 )
 
 func main() {
-	r := router.SetupRouter()
-
-	log.Println("Server starting on :8080")
-	if err := r.Run(":8080"); err != nil {
-		log.Fatal("Failed to start server:", err)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "config" {
+		os.Exit(configCommand(args[1:]))
 	}
+	if len(args) > 0 && args[0] == "openapi" {
+		os.Exit(openapiCommand(args[1:]))
+	}
+	os.Exit(run(args))
 }
 
+// run serves until the process is signalled and returns the exit code.
+func run(args []string) int {
+	cfg, err := config.Load(args)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		slog.Error("failed to load configuration", "error", err)
+		return 2
+	}
+	if err := cfg.Validate(); err != nil {
+		slog.Error("invalid configuration", "error", err)
+		return 2
+	}
+
+	logging.Setup(cfg.Log.Level, cfg.Log.Format)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing.Exporter)
+	if err != nil {
+		slog.Error("failed to configure tracing", "error", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// A signal while MongoDB is still unreachable stops the retries.
+	if err := database.Connect(ctx, cfg.Database); err != nil {
+		slog.Error("failed to connect to MongoDB", "error", err)
+		shutdownTracing(context.Background())
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              cfg.Server.Addr,
+		Handler:           router.SetupRouter(cfg),
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "addr", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case err := <-serveErr:
+		slog.Error("failed to start server", "error", err)
+		exitCode = 1
+	case <-ctx.Done():
+		stop()
+		slog.Info("shutting down", "timeout", cfg.Server.ShutdownTimeout.String())
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			slog.Error("error draining connections", "error", err)
+			exitCode = 1
+		}
+		if err := <-serveErr; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("server error", "error", err)
+			exitCode = 1
+		}
+	}
+
+	cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := database.Disconnect(cleanupCtx); err != nil {
+		slog.Error("error disconnecting from MongoDB", "error", err)
+	}
+	if err := shutdownTracing(cleanupCtx); err != nil {
+		slog.Error("error flushing traces", "error", err)
+	}
+
+	slog.Info("server stopped")
+	return exitCode
+}
@@ -0,0 +1,203 @@
+// Package metrics exposes Prometheus metrics for the server: HTTP traffic,
+// MongoDB commands, logins and task counts. Everything is registered on
+// Registry rather than the global default registry.
+package metrics
+
+import (
+	"context"
+	"crypto/subtle"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"task_manager/problem"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.mongodb.org/mongo-driver/event"
+)
+
+const namespace = "task_manager"
+
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	mongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mongodb_command_duration_seconds",
+		Help:      "MongoDB command latency by collection and command.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"collection", "command"})
+
+	mongoErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "mongodb_command_errors_total",
+		Help:      "Failed MongoDB commands by collection and command.",
+	}, []string{"collection", "command"})
+
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Login attempts by result (success or failure).",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, mongoDuration, mongoErrors, logins,
+	)
+	// Start both series at zero so rate() works before the first failure.
+	logins.WithLabelValues("success")
+	logins.WithLabelValues("failure")
+}
+
+// Middleware records every request under its route template, so /tasks/1
+// and /tasks/2 share a series. Requests matching no route are grouped as
+// "unmatched" to keep the number of series bounded.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// RecordLogin counts a login attempt; success is false for any rejected
+// attempt, including throttled ones.
+func RecordLogin(success bool) {
+	result := "failure"
+	if success {
+		result = "success"
+	}
+	logins.WithLabelValues(result).Inc()
+}
+
+// CommandMonitor times MongoDB commands. Install it on every client with
+// options.Client().SetMonitor.
+func CommandMonitor() *event.CommandMonitor {
+	// Only the started event names the collection, so remember it until
+	// the command finishes.
+	var collections sync.Map
+
+	finish := func(requestID int64, command string, duration time.Duration, failed bool) {
+		collection := "unknown"
+		if value, ok := collections.LoadAndDelete(requestID); ok {
+			collection = value.(string)
+		}
+
+		mongoDuration.WithLabelValues(collection, command).Observe(duration.Seconds())
+		if failed {
+			mongoErrors.WithLabelValues(collection, command).Inc()
+		}
+	}
+
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, e *event.CommandStartedEvent) {
+			// The first element of a command is its name, with the
+			// collection as the value for collection-level commands.
+			if element, err := e.Command.IndexErr(0); err == nil {
+				if collection, ok := element.Value().StringValueOK(); ok {
+					collections.Store(e.RequestID, collection)
+				}
+			}
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finish(e.RequestID, e.CommandName, e.Duration, false)
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finish(e.RequestID, e.CommandName, e.Duration, true)
+		},
+	}
+}
+
+// TaskCounter reports how many tasks are open and done.
+type TaskCounter func(ctx context.Context) (open, done int64, err error)
+
+type taskCollector struct {
+	count atomic.Pointer[TaskCounter]
+	desc  *prometheus.Desc
+}
+
+var (
+	tasks = &taskCollector{
+		desc: prometheus.NewDesc(namespace+"_tasks", "Tasks in all workspaces by status (open or done).",
+			[]string{"status"}, nil),
+	}
+	registerTasks sync.Once
+)
+
+// RegisterTaskCounts adds a gauge of tasks by status, computed with count
+// on every scrape. Calling it again only replaces count, so the router can be
+// built more than once in a process, as tests do.
+func RegisterTaskCounts(count TaskCounter) {
+	tasks.count.Store(&count)
+	registerTasks.Do(func() {
+		Registry.MustRegister(tasks)
+	})
+}
+
+func (tc *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- tc.desc
+}
+
+func (tc *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	open, done, err := (*tc.count.Load())(ctx)
+	if err != nil {
+		slog.Error("error counting tasks for metrics", "error", err)
+		ch <- prometheus.NewInvalidMetric(tc.desc, err)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(tc.desc, prometheus.GaugeValue, float64(open), "open")
+	ch <- prometheus.MustNewConstMetric(tc.desc, prometheus.GaugeValue, float64(done), "done")
+}
+
+// Handler serves Registry in the Prometheus text format. If token is not
+// empty, scrapers must send it as a bearer token.
+func Handler(token string) gin.HandlerFunc {
+	// Keep serving the other metrics when the task count fails, e.g. while
+	// the database is down.
+	handler := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+
+	return func(c *gin.Context) {
+		if token != "" {
+			expected := []byte("Bearer " + token)
+			if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), expected) != 1 {
+				problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+				return
+			}
+		}
+
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -1,31 +1,48 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	database "task_manager/data"
+	"task_manager/problem"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 )
 
-var jwtSecret = []byte("Mysecretkey")
+// jwtSecret signs every token the server issues; see SetJWTSecret.
+var jwtSecret []byte
+
+// SetJWTSecret must be called at startup, before any token is issued or
+// checked.
+func SetJWTSecret(secret string) {
+	jwtSecret = []byte(secret)
+}
 
 type Claims struct {
-	UserID   int    `json:"user_id"`
-	Username string `json:"username"`
-	Role     string `json:"role"`
+	UserID       int    `json:"user_id"`
+	Username     string `json:"username"`
+	Role         string `json:"role"`
+	TokenVersion int    `json:"token_version"`
+	SessionID    int    `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-func GenerateToken(userID int, username, role string) (string, error) {
-	now := time.Now()
+// GenerateToken issues a login token for the session, expiring with it.
+func GenerateToken(userID int, username, role string, tokenVersion, sessionID int, expiresAt time.Time) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
-		Role:     role,
+		UserID:       userID,
+		Username:     username,
+		Role:         role,
+		TokenVersion: tokenVersion,
+		SessionID:    sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(now.Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 	}
 
@@ -42,60 +59,186 @@ func ValidateToken(tokenString string) (*Claims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	// Purpose-specific tokens such as invitations carry an audience; only
+	// audience-less tokens are accepted as session tokens.
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && len(claims.Audience) == 0 {
 		return claims, nil
 	}
 
 	return nil, jwt.ErrSignatureInvalid
 }
 
+var tracer = otel.Tracer("task_manager/middleware")
+
+// AuthMiddleware authenticates the request with a login token or an API
+// token. The check gets its own span so its cost can be told apart from the
+// handler's.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "authorization header required"})
-			c.Abort()
-			return
+		request := c.Request
+		ctx, span := tracer.Start(request.Context(), "AuthMiddleware")
+		c.Request = request.WithContext(ctx)
+
+		ok := authenticate(c)
+		if ok {
+			span.SetAttributes(attribute.Int("enduser.id", c.GetInt("user_id")))
+		} else {
+			span.SetStatus(codes.Error, "unauthenticated")
 		}
+		span.End()
+		c.Request = request
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header format"})
-			c.Abort()
-			return
+		if ok {
+			c.Next()
 		}
+	}
+}
 
-		tokenString := parts[1]
+// authenticate sets the user context keys, or writes a 401 and aborts.
+func authenticate(c *gin.Context) bool {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAuthorizationRequired))
+		return false
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidAuthorizationHeader))
+		return false
+	}
+
+	tokenString := parts[1]
+	var user database.UserModel
+	if database.IsAPIToken(tokenString) {
+		apiToken, err := database.GetActiveAPIToken(c.Request.Context(), tokenString)
+		if err != nil {
+			abortLookup(c, err, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+			return false
+		}
+
+		user, err = database.GetUserByID(c.Request.Context(), apiToken.UserID)
+		if err != nil {
+			abortLookup(c, err, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+			return false
+		}
+
+		c.Set("api_token_id", apiToken.ID)
+		c.Set("token_scopes", apiToken.Scopes)
+	} else {
 		claims, err := ValidateToken(tokenString)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid or expired token"})
-			c.Abort()
-			return
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+			return false
 		}
 
-		c.Set("user_id", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
-		c.Next()
+		// Load the account on every request so that disabling, deleting or
+		// demoting a user takes effect before their token expires.
+		user, err = database.GetUserByID(c.Request.Context(), claims.UserID)
+		if err != nil {
+			abortLookup(c, err, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+			return false
+		}
+		if user.TokenVersion != claims.TokenVersion {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeInvalidToken))
+			return false
+		}
+
+		if _, err := database.GetActiveSession(c.Request.Context(), user.ID, claims.SessionID); err != nil {
+			abortLookup(c, err, problem.New(http.StatusUnauthorized, problem.CodeSessionExpired))
+			return false
+		}
+
+		c.Set("session_id", claims.SessionID)
 	}
+
+	if user.Disabled {
+		problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAccountDisabled))
+		return false
+	}
+
+	c.Set("user_id", user.ID)
+	c.Set("username", user.Username)
+	c.Set("role", user.Role)
+	c.Set("two_factor_enabled", user.TwoFactorEnabled)
+	c.Set("service_account", user.ServiceAccount)
+	return true
+}
+
+// abortLookup rejects the request after a failed database lookup. A missing
+// record is answered with notFound; any other failure is the server's and is
+// reported as such.
+func abortLookup(c *gin.Context, err error, notFound *problem.Problem) {
+	if errors.Is(err, database.ErrNotFound) {
+		problem.Abort(c, notFound.WithCause(err))
+		return
+	}
+	problem.Abort(c, err)
 }
 
 func AdminMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		role, exists := c.Get("role")
 		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-			c.Abort()
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAuthorizationRequired))
 			return
 		}
 
 		if role != "admin" {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
-			c.Abort()
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAdminRequired))
 			return
 		}
 
+		// Service accounts cannot enroll in 2FA; their tokens are issued by
+		// an admin instead.
+		if !c.GetBool("two_factor_enabled") && !c.GetBool("service_account") {
+			policy, err := database.GetSecurityPolicy(c.Request.Context())
+			if err != nil {
+				problem.Abort(c, err)
+				return
+			}
+
+			// Admins without 2FA can still reach /me/2fa to enroll, which is
+			// not behind this middleware.
+			if policy.RequireAdminTwoFactor {
+				problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeTwoFactorRequired))
+				return
+			}
+		}
+
 		c.Next()
 	}
 }
 
+// RequireScope limits API-token requests to tokens granted scope. Requests
+// authenticated with a login token are not restricted.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIToken := c.Get("api_token_id"); !isAPIToken {
+			c.Next()
+			return
+		}
+
+		for _, granted := range c.GetStringSlice("token_scopes") {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeInsufficientScope).With("scope", scope))
+	}
+}
+
+// SessionOnly rejects API tokens, for endpoints such as account management
+// that should need an interactive login.
+func SessionOnly() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isAPIToken := c.Get("api_token_id"); isAPIToken {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeAPITokenNotAllowed))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+
+	database "task_manager/data"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Problems writes the response for a request that was aborted with
+// problem.Abort. It must run after the logging and metrics middleware, so
+// they see the final status, and before every handler.
+func Problems() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+		problem.Write(c, toProblem(c, c.Errors.Last().Err))
+	}
+}
+
+// toProblem maps an error to the problem sent to the client. Failures of the
+// data layer carry their own code; anything unknown becomes a 500 whose
+// cause is only logged.
+func toProblem(c *gin.Context, err error) *problem.Problem {
+	var p *problem.Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var dataErr *database.Error
+	errors.As(err, &dataErr)
+
+	switch {
+	case errors.Is(err, database.ErrUnavailable):
+		c.Header("Retry-After", "5")
+		return problem.New(http.StatusServiceUnavailable, problem.CodeUnavailable).WithCause(err)
+	case errors.Is(err, database.ErrNotFound) && dataErr != nil:
+		return problem.New(http.StatusNotFound, problem.Code(dataErr.Code)).WithCause(err)
+	case errors.Is(err, database.ErrConflict) && dataErr != nil:
+		return problem.New(http.StatusConflict, problem.Code(dataErr.Code)).WithCause(err)
+	}
+	return problem.New(http.StatusInternalServerError, problem.CodeInternal).WithCause(err)
+}
+
+// NoRoute answers requests for paths without a handler.
+func NoRoute(c *gin.Context) {
+	problem.Abort(c, problem.New(http.StatusNotFound, problem.CodeRouteNotFound))
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"task_manager/problem"
+	"task_manager/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit gives each client its own bucket per name. Clients are told
+// apart by the user in a valid login token, or else by IP. It runs before
+// AuthMiddleware, so rejected requests cost no database lookups; API tokens
+// would need one to identify their user and are counted by IP.
+//
+// If the store fails the request is let through: an outage of a shared
+// store should not take the API down with it.
+func RateLimit(store ratelimit.Store, name string, limit ratelimit.Limit) gin.HandlerFunc {
+	// The policy gives the burst as the quota of the window it takes to
+	// refill.
+	policy := fmt.Sprintf("%d;w=%d", limit.Burst, int(math.Ceil(float64(limit.Burst)/float64(limit.Requests)*limit.Per.Seconds())))
+	return func(c *gin.Context) {
+		result, err := store.Take(c.Request.Context(), name+":"+rateLimitKey(c), limit)
+		if err != nil {
+			slog.WarnContext(c.Request.Context(), "rate limit store failed, allowing request", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", policy)
+		c.Header("RateLimit-Limit", strconv.Itoa(limit.Burst))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", ceilSeconds(result.Reset))
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("Retry-After", retryAfter)
+			problem.Abort(c, problem.New(http.StatusTooManyRequests, problem.CodeRateLimited).With("seconds", retryAfter))
+			return
+		}
+		c.Next()
+	}
+}
+
+// rateLimitKey identifies the client. The token's signature is checked, so
+// a forged user ID cannot drain another user's bucket, but not its session,
+// which AuthMiddleware does next.
+func rateLimitKey(c *gin.Context) string {
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok {
+		if claims, err := ValidateToken(token); err == nil {
+			return "user:" + strconv.Itoa(claims.UserID)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	twoFactorAudience     = "2fa_challenge"
+	twoFactorChallengeTTL = 5 * time.Minute
+)
+
+// GenerateTwoFactorChallenge issues the short-lived token Login returns when
+// the password was correct but a TOTP code is still needed. It cannot be used
+// as a session token because it carries an audience.
+func GenerateTwoFactorChallenge(userID int) (string, error) {
+	now := time.Now()
+	claims := jwt.RegisteredClaims{
+		Subject:   strconv.Itoa(userID),
+		Audience:  jwt.ClaimStrings{twoFactorAudience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(twoFactorChallengeTTL)),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
+}
+
+func ValidateTwoFactorChallenge(tokenString string) (int, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return jwtSecret, nil
+	}, jwt.WithAudience(twoFactorAudience), jwt.WithExpirationRequired())
+
+	if err != nil {
+		return 0, err
+	}
+
+	claims, ok := token.Claims.(*jwt.RegisteredClaims)
+	if !ok || !token.Valid {
+		return 0, jwt.ErrSignatureInvalid
+	}
+
+	return strconv.Atoi(claims.Subject)
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	database "task_manager/data"
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkspaceMiddleware resolves the :ws route parameter and only lets the
+// request through when the authenticated user is a member of that workspace.
+// It must run after AuthMiddleware.
+func WorkspaceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		workspaceID, err := strconv.Atoi(c.Param("ws"))
+		if err != nil {
+			problem.Abort(c, problem.InvalidParameter("ws"))
+			return
+		}
+
+		userID, exists := c.Get("user_id")
+		if !exists {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAuthorizationRequired))
+			return
+		}
+
+		role, err := database.GetWorkspaceRole(c.Request.Context(), workspaceID, userID.(int))
+		if err != nil {
+			// Non-members get the same response as a missing workspace so
+			// workspace IDs cannot be probed.
+			abortLookup(c, err, problem.New(http.StatusNotFound, problem.CodeWorkspaceNotFound))
+			return
+		}
+
+		c.Set("workspace_id", workspaceID)
+		c.Set("workspace_role", role)
+		c.Next()
+	}
+}
+
+func WorkspaceAdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("workspace_role")
+		if !exists {
+			problem.Abort(c, problem.New(http.StatusUnauthorized, problem.CodeAuthorizationRequired))
+			return
+		}
+
+		if role != database.WorkspaceRoleAdmin {
+			problem.Abort(c, problem.New(http.StatusForbidden, problem.CodeWorkspaceAdminRequired))
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,110 @@
+package models
+
+import database "task_manager/data"
+
+// Response bodies that are not a stored record as is. They are also the
+// schemas of the OpenAPI document, so a field added here is documented.
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type RegisterResponse struct {
+	ID            int    `json:"id"`
+	Username      string `json:"username"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"emailVerified"`
+	Role          string `json:"role"`
+}
+
+// LoginResponse carries either a token or, for accounts with two-factor
+// authentication, a challenge to complete at /auth/login/2fa.
+type LoginResponse struct {
+	Token             string `json:"token,omitempty"`
+	Username          string `json:"username,omitempty"`
+	Role              string `json:"role,omitempty"`
+	TwoFactorRequired bool   `json:"twoFactorRequired,omitempty"`
+	ChallengeToken    string `json:"challengeToken,omitempty"`
+}
+
+// SSOLinkResponse is where to send the browser to link an identity
+// provider account.
+type SSOLinkResponse struct {
+	AuthorizationURL string `json:"authorizationUrl"`
+}
+
+type AcceptInvitationResponse struct {
+	ID          int    `json:"id"`
+	Username    string `json:"username"`
+	Role        string `json:"role"`
+	WorkspaceID int    `json:"workspaceId"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+	Token   string `json:"token"`
+}
+
+// AccountExport is everything stored about a user.
+type AccountExport struct {
+	User       database.UserModel        `json:"user"`
+	Tasks      []database.TaskModel      `json:"tasks"`
+	Workspaces []database.WorkspaceModel `json:"workspaces"`
+}
+
+type DeleteAccountResponse struct {
+	Message string        `json:"message"`
+	Export  AccountExport `json:"export"`
+}
+
+type EnrollTwoFactorResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauthUri"`
+}
+
+type ConfirmTwoFactorResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
+
+// CreateAPITokenResponse holds the only copy of the token.
+type CreateAPITokenResponse struct {
+	APIToken database.APITokenModel `json:"apiToken"`
+	Token    string                 `json:"token"`
+}
+
+type CreateInvitationResponse struct {
+	Invitation database.InvitationModel `json:"invitation"`
+	Token      string                   `json:"token"`
+}
+
+type RevokeSessionsResponse struct {
+	Message string `json:"message"`
+	Revoked int64  `json:"revoked"`
+}
+
+type UserPage struct {
+	Users []database.UserModel `json:"users"`
+	Total int64                `json:"total"`
+	Page  int                  `json:"page"`
+	Limit int                  `json:"limit"`
+}
+
+type AuditPage struct {
+	Entries []database.AuditEntryModel `json:"entries"`
+	Total   int64                      `json:"total"`
+	Page    int                        `json:"page"`
+	Limit   int                        `json:"limit"`
+}
+
+type DeleteUserResponse struct {
+	Message         string `json:"message"`
+	ReassignedTo    int    `json:"reassignedTo"`
+	ReassignedTasks int64  `json:"reassignedTasks"`
+}
+
+// HealthResponse is the body of the health probes.
+type HealthResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
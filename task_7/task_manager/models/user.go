@@ -15,9 +15,77 @@ type LoginRequest struct {
 type RegisterRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
 }
 
 type PromoteRequest struct {
 	Username string `json:"username" binding:"required"`
 }
 
+type UpdateUserRequest struct {
+	Role     *string `json:"role" binding:"omitempty,oneof=user admin"`
+	Disabled *bool   `json:"disabled"`
+}
+
+type UpdateProfileRequest struct {
+	DisplayName *string `json:"displayName" binding:"omitempty,max=100"`
+	Email       *string `json:"email" binding:"omitempty,email"`
+	Timezone    *string `json:"timezone" binding:"omitempty,timezone"`
+}
+
+type ChangePasswordRequest struct {
+	// CurrentPassword is not needed by accounts without a password.
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" binding:"required"`
+}
+
+type DeleteAccountRequest struct {
+	// Password is not needed by accounts without one.
+	Password string `json:"password"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// LoginTwoFactorRequest completes a login that returned a challenge token.
+// Exactly one of Code and RecoveryCode must be set.
+type LoginTwoFactorRequest struct {
+	ChallengeToken string `json:"challengeToken" binding:"required"`
+	Code           string `json:"code" binding:"required_without=RecoveryCode,excluded_with=RecoveryCode"`
+	RecoveryCode   string `json:"recoveryCode"`
+}
+
+type ConfirmTwoFactorRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableTwoFactorRequest struct {
+	// Password is not needed by accounts without one.
+	Password string `json:"password"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type SecurityPolicyRequest struct {
+	RequireAdminTwoFactor *bool `json:"requireAdminTwoFactor" binding:"required"`
+}
+
+type CreateAPITokenRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1,dive,oneof=tasks:read tasks:write workspaces:write admin"`
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=365"`
+}
+
+type CreateServiceAccountRequest struct {
+	Name string `json:"name" binding:"required"`
+	Role string `json:"role" binding:"omitempty,oneof=user admin"`
+}
@@ -0,0 +1,304 @@
+// Package openapi builds the OpenAPI 3.1 description of the API from the
+// routes registered on the gin engine and the structs the handlers bind and
+// return. Each route needs an entry in operations.go; Check reports routes
+// and entries that do not match.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+
+	"task_manager/problem"
+
+	"github.com/gin-gonic/gin"
+)
+
+const Version = "3.1.0"
+
+var info = Info{Title: "Task Manager API", Version: "1.0.0"}
+
+// Operation describes one route. Request and Response are values of the
+// body types, such as models.LoginRequest{}; a nil Response means the body
+// is not JSON or not described.
+type Operation struct {
+	ID      string
+	Summary string
+	Request any
+	// Response is sent with Status, or 200 if Status is zero.
+	Response any
+	Status   int
+	// ContentTypes of the response, if it is not JSON.
+	ContentTypes []string
+	Query        []Param
+	// Public routes need no token.
+	Public bool
+	// Optional routes are only registered with some configuration, such as
+	// SSO.
+	Optional bool
+}
+
+// Param is a query parameter.
+type Param struct {
+	Name        string
+	Type        string
+	Description string
+	Required    bool
+}
+
+// Document is the OpenAPI document.
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components components                      `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Tags        []string              `json:"tags"`
+	Parameters  []parameter           `json:"parameters,omitempty"`
+	RequestBody *body                 `json:"requestBody,omitempty"`
+	Responses   map[string]response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required"`
+	Schema      *Schema `json:"schema"`
+}
+
+type body struct {
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]mediaType `json:"content,omitempty"`
+}
+
+type components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	Responses       map[string]response       `json:"responses"`
+	SecuritySchemes map[string]securityScheme `json:"securitySchemes"`
+}
+
+type securityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// pathParam matches gin's ":name" path segments.
+var pathParam = regexp.MustCompile(`:(\w+)`)
+
+// Build describes routes. Routes without an entry in operations are still
+// listed, with only their path parameters.
+func Build(routes gin.RoutesInfo) *Document {
+	s := &schemas{components: map[string]*Schema{}}
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]map[string]operation{},
+		Components: components{
+			Schemas: s.components,
+			Responses: map[string]response{
+				"Problem": {
+					Description: "The request failed; see code.",
+					Content:     map[string]mediaType{problem.ContentType: {Schema: problemSchema()}},
+				},
+			},
+			SecuritySchemes: map[string]securityScheme{
+				"bearerAuth": {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "A login token from /auth/login or an API token.",
+				},
+			},
+		},
+	}
+
+	for _, route := range routes {
+		op := operations[route.Method+" "+route.Path]
+		path := pathParam.ReplaceAllString(route.Path, "{$1}")
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]operation{}
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = describe(s, route, op)
+	}
+	return doc
+}
+
+func describe(s *schemas, route gin.RouteInfo, op Operation) operation {
+	id := op.ID
+	if id == "" {
+		id = strings.ToLower(route.Method) + strings.NewReplacer("/", "_", ":", "").Replace(route.Path)
+	}
+	described := operation{
+		OperationID: id,
+		Summary:     op.Summary,
+		Tags:        []string{tag(route.Path)},
+		Responses:   map[string]response{"default": {Ref: "#/components/responses/Problem"}},
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		described.Parameters = append(described.Parameters, parameter{
+			Name: match[1], In: "path", Required: true, Schema: &Schema{Type: "integer"},
+		})
+	}
+	for _, param := range op.Query {
+		described.Parameters = append(described.Parameters, parameter{
+			Name: param.Name, In: "query", Description: param.Description,
+			Required: param.Required, Schema: &Schema{Type: param.Type},
+		})
+	}
+
+	if op.Request != nil {
+		described.RequestBody = &body{
+			Required: true,
+			Content:  map[string]mediaType{"application/json": {Schema: s.of(reflect.TypeOf(op.Request))}},
+		}
+	}
+
+	status := op.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := response{Description: http.StatusText(status)}
+	switch {
+	case len(op.ContentTypes) > 0:
+		success.Content = map[string]mediaType{}
+		for _, contentType := range op.ContentTypes {
+			success.Content[contentType] = mediaType{}
+		}
+	case op.Response != nil:
+		success.Content = map[string]mediaType{"application/json": {Schema: s.of(reflect.TypeOf(op.Response))}}
+	}
+	described.Responses[fmt.Sprint(status)] = success
+
+	if !op.Public {
+		described.Security = []map[string][]string{{"bearerAuth": {}}}
+	}
+	return described
+}
+
+// tag groups operations by the first path segment; the probes and other
+// operational endpoints share one group.
+func tag(path string) string {
+	first, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	switch first {
+	case "auth", "admin", "me", "workspaces":
+		return first
+	}
+	return "operations"
+}
+
+func problemSchema() *Schema {
+	str := &Schema{Type: "string"}
+	return &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"type":      str,
+			"title":     str,
+			"status":    {Type: "integer"},
+			"code":      {Type: "string", Description: "Stable, machine-readable error code."},
+			"detail":    str,
+			"instance":  str,
+			"requestId": str,
+			"errors": {Type: "array", Items: &Schema{
+				Type: "object",
+				Properties: map[string]*Schema{
+					"field":   str,
+					"code":    str,
+					"message": str,
+				},
+			}},
+		},
+		Required: []string{"type", "title", "status", "code"},
+	}
+}
+
+// Check reports routes without an entry in operations, and entries without
+// a route unless they are optional.
+func Check(routes gin.RoutesInfo) error {
+	registered := map[string]bool{}
+	var errs []error
+	for _, route := range routes {
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := operations[key]; !ok {
+			errs = append(errs, fmt.Errorf("route %s is not described", key))
+		}
+	}
+
+	keys := make([]string, 0, len(operations))
+	for key := range operations {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !registered[key] && !operations[key].Optional {
+			errs = append(errs, fmt.Errorf("operation %s has no route", key))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Register serves the document at /openapi.json and the reference at /docs.
+// It must be called after every other route is registered, since the
+// document is built once from the routes on r.
+func Register(r *gin.Engine) error {
+	var encoded []byte
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json", encoded)
+	})
+	r.GET("/docs", ui)
+	r.GET("/docs/redoc.standalone.js", redoc)
+
+	var err error
+	encoded, err = json.Marshal(Build(r.Routes()))
+	return err
+}
+
+//go:embed redoc.html
+var redocPage []byte
+
+// redocScript is the unmodified redoc.standalone.js of Redoc 2.0.0-rc.59
+// (github.com/Redocly/redoc), under the MIT license in redoc.LICENSE. It is
+// served from the binary so that /docs runs no third-party code the build
+// has not pinned.
+//
+//go:embed redoc.standalone.js
+var redocScript []byte
+
+// ui serves a page that renders /openapi.json with Redoc.
+func ui(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", redocPage)
+}
+
+func redoc(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "text/javascript; charset=utf-8", redocScript)
+}
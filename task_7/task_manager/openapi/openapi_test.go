@@ -0,0 +1,101 @@
+package openapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"task_manager/config"
+	"task_manager/openapi"
+	"task_manager/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newEngine builds the router like "task_manager openapi check", without a
+// database.
+func newEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	cfg := config.Default()
+	cfg.Auth.JWTSecret = "openapi-test-secret-0123456789abcdef"
+	return router.SetupRouter(cfg)
+}
+
+func TestEveryRouteIsDescribed(t *testing.T) {
+	if err := openapi.Check(newEngine().Routes()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+var (
+	ginParam      = regexp.MustCompile(`[:*](\w+)`)
+	templateParam = regexp.MustCompile(`\{(\w+)\}`)
+)
+
+func TestPathParametersMatchRoutes(t *testing.T) {
+	routes := newEngine().Routes()
+	doc := openapi.Build(routes)
+
+	for _, route := range routes {
+		var want []string
+		for _, match := range ginParam.FindAllStringSubmatch(route.Path, -1) {
+			want = append(want, match[1])
+		}
+
+		path := ginParam.ReplaceAllString(route.Path, "{$1}")
+		op, ok := doc.Paths[path][strings.ToLower(route.Method)]
+		if !ok {
+			t.Errorf("%s %s is missing from the document as %s", route.Method, route.Path, path)
+			continue
+		}
+
+		var inTemplate []string
+		for _, match := range templateParam.FindAllStringSubmatch(path, -1) {
+			inTemplate = append(inTemplate, match[1])
+		}
+		var documented, query []string
+		for _, param := range op.Parameters {
+			switch param.In {
+			case "path":
+				documented = append(documented, param.Name)
+				if !param.Required {
+					t.Errorf("%s %s: path parameter %s is not required", route.Method, route.Path, param.Name)
+				}
+			case "query":
+				query = append(query, param.Name)
+			}
+		}
+
+		if !slices.Equal(documented, want) || !slices.Equal(inTemplate, want) {
+			t.Errorf("%s %s: path parameters %v in %s, want %v", route.Method, route.Path, documented, path, want)
+		}
+		for _, name := range query {
+			if slices.Contains(want, name) {
+				t.Errorf("%s %s: query parameter %s shadows the path parameter", route.Method, route.Path, name)
+			}
+		}
+	}
+}
+
+func TestDocsServeRedocLocally(t *testing.T) {
+	engine := newEngine()
+	page := httptest.NewRecorder()
+	engine.ServeHTTP(page, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	if page.Code != http.StatusOK {
+		t.Fatalf("GET /docs = %d", page.Code)
+	}
+	for _, src := range regexp.MustCompile(`<script src="([^"]+)"`).FindAllStringSubmatch(page.Body.String(), -1) {
+		if !strings.HasPrefix(src[1], "/") {
+			t.Errorf("/docs loads %s from another origin", src[1])
+			continue
+		}
+		script := httptest.NewRecorder()
+		engine.ServeHTTP(script, httptest.NewRequest(http.MethodGet, src[1], nil))
+		if script.Code != http.StatusOK || script.Body.Len() == 0 {
+			t.Errorf("GET %s = %d with %d bytes", src[1], script.Code, script.Body.Len())
+		}
+	}
+}
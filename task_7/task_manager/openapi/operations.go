@@ -0,0 +1,226 @@
+package openapi
+
+import (
+	"net/http"
+
+	database "task_manager/data"
+	"task_manager/models"
+)
+
+var pageParams = []Param{
+	{Name: "page", Type: "integer", Description: "1-based page number; default 1."},
+	{Name: "limit", Type: "integer", Description: "Page size; default 20, at most 100."},
+}
+
+var auditFilterParams = []Param{
+	{Name: "actorId", Type: "integer"},
+	{Name: "action", Type: "string"},
+	{Name: "targetType", Type: "string"},
+	{Name: "targetId", Type: "string"},
+	{Name: "result", Type: "string", Description: "success or failure."},
+	{Name: "from", Type: "string", Description: "RFC 3339 time; lower bound."},
+	{Name: "to", Type: "string", Description: "RFC 3339 time; upper bound."},
+}
+
+// operations describes every route, keyed by method and gin path.
+var operations = map[string]Operation{
+	"GET /healthz": {ID: "healthz", Summary: "Liveness probe", Response: models.HealthResponse{}, Public: true},
+	"GET /readyz":  {ID: "readyz", Summary: "Readiness probe; 503 while the database is unreachable", Response: models.HealthResponse{}, Public: true},
+	"GET /metrics": {
+		ID: "metrics", Summary: "Prometheus metrics; needs the metrics token if one is configured",
+		ContentTypes: []string{"text/plain"},
+	},
+	"GET /openapi.json": {ID: "getOpenAPI", Summary: "This document", ContentTypes: []string{"application/json"}, Public: true},
+	"GET /docs":         {ID: "getDocs", Summary: "API reference rendered with Redoc", ContentTypes: []string{"text/html"}, Public: true},
+	"GET /docs/redoc.standalone.js": {
+		ID: "getRedoc", Summary: "The Redoc script used by /docs", ContentTypes: []string{"text/javascript"}, Public: true,
+	},
+
+	"POST /auth/register": {
+		ID: "register", Summary: "Create an account",
+		Request: models.RegisterRequest{}, Response: models.RegisterResponse{}, Status: http.StatusCreated, Public: true,
+	},
+	"POST /auth/login": {
+		ID: "login", Summary: "Sign in with a password",
+		Request: models.LoginRequest{}, Response: models.LoginResponse{}, Public: true,
+	},
+	"POST /auth/login/2fa": {
+		ID: "loginTwoFactor", Summary: "Complete a login challenge with a one-time or recovery code",
+		Request: models.LoginTwoFactorRequest{}, Response: models.LoginResponse{}, Public: true,
+	},
+	"POST /auth/accept-invitation": {
+		ID: "acceptInvitation", Summary: "Accept an invitation; 201 when it creates the account",
+		Request: models.AcceptInvitationRequest{}, Response: models.AcceptInvitationResponse{}, Status: http.StatusCreated, Public: true,
+	},
+	"POST /auth/forgot-password": {
+		ID: "forgotPassword", Summary: "Email a password reset link",
+		Request: models.ForgotPasswordRequest{}, Response: models.MessageResponse{}, Public: true,
+	},
+	"POST /auth/reset-password": {
+		ID: "resetPassword", Summary: "Set a new password with a reset token",
+		Request: models.ResetPasswordRequest{}, Response: models.MessageResponse{}, Public: true,
+	},
+	"POST /auth/verify-email": {
+		ID: "verifyEmail", Summary: "Verify an email address",
+		Request: models.VerifyEmailRequest{}, Response: models.MessageResponse{}, Public: true,
+	},
+	"GET /auth/oidc/login": {
+		ID: "ssoLogin", Summary: "Redirect to the identity provider",
+		Status: http.StatusFound, Public: true, Optional: true,
+	},
+	"GET /auth/oidc/callback": {
+		ID: "ssoCallback", Summary: "Complete single sign-on",
+		Response: models.LoginResponse{}, Public: true, Optional: true,
+		Query: []Param{
+			{Name: "code", Type: "string"},
+			{Name: "state", Type: "string", Required: true},
+			{Name: "error", Type: "string", Description: "Set by the provider when sign-in failed."},
+		},
+	},
+
+	"POST /admin/promote": {
+		ID: "promoteUser", Summary: "Make a user an admin",
+		Request: models.PromoteRequest{}, Response: models.MessageResponse{},
+	},
+	"GET /admin/users": {
+		ID: "listUsers", Summary: "List users",
+		Response: models.UserPage{},
+		Query:    append([]Param{{Name: "search", Type: "string", Description: "Matches username, display name or email."}}, pageParams...),
+	},
+	"PATCH /admin/users/:id": {
+		ID: "updateUser", Summary: "Change a user's role or disable the account",
+		Request: models.UpdateUserRequest{}, Response: database.UserModel{},
+	},
+	"DELETE /admin/users/:id": {
+		ID: "deleteUser", Summary: "Delete a user",
+		Response: models.DeleteUserResponse{},
+		Query:    []Param{{Name: "reassignTo", Type: "integer", Description: "User who takes over the deleted user's tasks."}},
+	},
+	"DELETE /admin/users/:id/sessions": {
+		ID: "revokeUserSessions", Summary: "Sign a user out everywhere",
+		Response: models.RevokeSessionsResponse{},
+	},
+	"GET /admin/security-policy": {ID: "getSecurityPolicy", Summary: "Get the security policy", Response: database.SecurityPolicy{}},
+	"PUT /admin/security-policy": {
+		ID: "updateSecurityPolicy", Summary: "Replace the security policy",
+		Request: models.SecurityPolicyRequest{}, Response: database.SecurityPolicy{},
+	},
+	"GET /admin/lockouts": {ID: "listLockouts", Summary: "List active login lockouts", Response: []database.LoginAttemptModel{}},
+	"DELETE /admin/lockouts": {
+		ID: "clearLockout", Summary: "Clear a lockout by username or IP",
+		Response: models.MessageResponse{},
+		Query: []Param{
+			{Name: "username", Type: "string"},
+			{Name: "ip", Type: "string", Description: "Takes precedence over username."},
+		},
+	},
+	"GET /admin/audit": {
+		ID: "listAudit", Summary: "Search the audit log, newest first",
+		Response: models.AuditPage{},
+		Query:    append(append([]Param{}, auditFilterParams...), pageParams...),
+	},
+	"GET /admin/audit/export": {
+		ID: "exportAudit", Summary: "Export matching audit entries, oldest first",
+		ContentTypes: []string{"application/x-ndjson", "text/csv"},
+		Query:        append(append([]Param{}, auditFilterParams...), Param{Name: "format", Type: "string", Description: "json (the default) or csv."}),
+	},
+	"GET /admin/audit/verify": {ID: "verifyAudit", Summary: "Verify the audit hash chain", Response: database.AuditVerification{}},
+	"POST /admin/invitations": {
+		ID: "createInvitation", Summary: "Invite a user to the instance",
+		Request: models.InviteUserRequest{}, Response: models.CreateInvitationResponse{}, Status: http.StatusCreated,
+	},
+	"GET /admin/invitations":        {ID: "listInvitations", Summary: "List pending invitations", Response: []database.InvitationModel{}},
+	"DELETE /admin/invitations/:id": {ID: "revokeInvitation", Summary: "Revoke an invitation", Response: models.MessageResponse{}},
+	"POST /admin/service-accounts": {
+		ID: "createServiceAccount", Summary: "Create a service account",
+		Request: models.CreateServiceAccountRequest{}, Response: database.UserModel{}, Status: http.StatusCreated,
+	},
+	"GET /admin/service-accounts": {ID: "listServiceAccounts", Summary: "List service accounts", Response: []database.UserModel{}},
+	"POST /admin/service-accounts/:id/tokens": {
+		ID: "createServiceAccountToken", Summary: "Issue an API token for a service account",
+		Request: models.CreateAPITokenRequest{}, Response: models.CreateAPITokenResponse{}, Status: http.StatusCreated,
+	},
+	"GET /admin/service-accounts/:id/tokens": {
+		ID: "listServiceAccountTokens", Summary: "List a service account's API tokens",
+		Response: []database.APITokenModel{},
+	},
+	"DELETE /admin/service-accounts/:id/tokens/:tokenId": {
+		ID: "revokeServiceAccountToken", Summary: "Revoke a service account's API token",
+		Response: models.MessageResponse{},
+	},
+
+	"GET /me": {ID: "getProfile", Summary: "Get your profile", Response: database.UserModel{}},
+	"PATCH /me": {
+		ID: "updateProfile", Summary: "Update your profile",
+		Request: models.UpdateProfileRequest{}, Response: database.UserModel{},
+	},
+	"DELETE /me": {
+		ID: "deleteAccount", Summary: "Delete your account",
+		Request: models.DeleteAccountRequest{}, Response: models.DeleteAccountResponse{},
+	},
+	"POST /me/password": {
+		ID: "changePassword", Summary: "Change your password and sign out other sessions",
+		Request: models.ChangePasswordRequest{}, Response: models.ChangePasswordResponse{},
+	},
+	"POST /me/email/verification": {
+		ID: "resendVerification", Summary: "Resend the verification email",
+		Response: models.MessageResponse{},
+	},
+	"POST /me/2fa/enroll": {ID: "enrollTwoFactor", Summary: "Start two-factor enrollment", Response: models.EnrollTwoFactorResponse{}},
+	"POST /me/2fa/confirm": {
+		ID: "confirmTwoFactor", Summary: "Confirm two-factor enrollment",
+		Request: models.ConfirmTwoFactorRequest{}, Response: models.ConfirmTwoFactorResponse{},
+	},
+	"DELETE /me/2fa": {
+		ID: "disableTwoFactor", Summary: "Disable two-factor authentication",
+		Request: models.DisableTwoFactorRequest{}, Response: models.MessageResponse{},
+	},
+	"GET /me/export": {ID: "exportAccount", Summary: "Download everything stored about you", Response: models.AccountExport{}},
+	"POST /me/tokens": {
+		ID: "createToken", Summary: "Issue a personal API token",
+		Request: models.CreateAPITokenRequest{}, Response: models.CreateAPITokenResponse{}, Status: http.StatusCreated,
+	},
+	"GET /me/tokens":                 {ID: "listTokens", Summary: "List your API tokens", Response: []database.APITokenModel{}},
+	"DELETE /me/tokens/:tokenId":     {ID: "revokeToken", Summary: "Revoke one of your API tokens", Response: models.MessageResponse{}},
+	"GET /me/sessions":               {ID: "listSessions", Summary: "List your active sessions", Response: []database.SessionModel{}},
+	"DELETE /me/sessions/:sessionId": {ID: "revokeSession", Summary: "Sign out one session", Response: models.MessageResponse{}},
+	"POST /me/sso/link": {
+		ID: "ssoLink", Summary: "Start linking an identity provider account; send the browser to the returned URL",
+		Response: models.SSOLinkResponse{}, Optional: true,
+	},
+
+	"POST /workspaces": {
+		ID: "createWorkspace", Summary: "Create a workspace",
+		Request: models.CreateWorkspaceRequest{}, Response: database.WorkspaceModel{}, Status: http.StatusCreated,
+	},
+	"GET /workspaces":     {ID: "listWorkspaces", Summary: "List your workspaces", Response: []database.WorkspaceModel{}},
+	"GET /workspaces/:ws": {ID: "getWorkspace", Summary: "Get a workspace", Response: database.WorkspaceModel{}},
+	"POST /workspaces/:ws/members": {
+		ID: "addMember", Summary: "Add a member",
+		Request: models.AddWorkspaceMemberRequest{}, Response: database.WorkspaceMember{}, Status: http.StatusCreated,
+	},
+	"DELETE /workspaces/:ws/members/:userId": {ID: "removeMember", Summary: "Remove a member", Response: models.MessageResponse{}},
+	"POST /workspaces/:ws/invitations": {
+		ID: "createWorkspaceInvitation", Summary: "Invite someone to the workspace",
+		Request: models.InviteWorkspaceMemberRequest{}, Response: models.CreateInvitationResponse{}, Status: http.StatusCreated,
+	},
+	"GET /workspaces/:ws/invitations": {
+		ID: "listWorkspaceInvitations", Summary: "List the workspace's pending invitations",
+		Response: []database.InvitationModel{},
+	},
+	"DELETE /workspaces/:ws/invitations/:id": {
+		ID: "revokeWorkspaceInvitation", Summary: "Revoke a workspace invitation",
+		Response: models.MessageResponse{},
+	},
+	"GET /workspaces/:ws/tasks": {ID: "listTasks", Summary: "List the workspace's tasks", Response: []database.TaskModel{}},
+	"POST /workspaces/:ws/tasks": {
+		ID: "createTask", Summary: "Create a task",
+		Request: database.TaskModel{}, Response: database.TaskModel{}, Status: http.StatusCreated,
+	},
+	"GET /workspaces/:ws/tasks/:id": {ID: "getTask", Summary: "Get a task", Response: database.TaskModel{}},
+	"PUT /workspaces/:ws/tasks/:id": {
+		ID: "updateTask", Summary: "Replace a task",
+		Request: database.TaskModel{}, Response: database.TaskModel{},
+	},
+	"DELETE /workspaces/:ws/tasks/:id": {ID: "deleteTask", Summary: "Delete a task", Response: models.MessageResponse{}},
+}
@@ -0,0 +1,195 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"task_manager/validation"
+)
+
+// Schema is the subset of JSON Schema the generator emits.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Enum                 []string           `json:"enum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *int               `json:"minimum,omitempty"`
+	Maximum              *int               `json:"maximum,omitempty"`
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemas turns Go types into schemas. Named structs become components and
+// are referenced, so each is described once.
+type schemas struct {
+	components map[string]*Schema
+}
+
+func (s *schemas) of(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		if _, seen := s.components[t.Name()]; !seen {
+			// Reserve the name first; a struct may refer to itself.
+			s.components[t.Name()] = nil
+			s.components[t.Name()] = s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + t.Name()}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		return s.object(t)
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: s.of(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.of(t.Elem())}
+	}
+	return &Schema{}
+}
+
+// object describes a struct's JSON fields, applying the binding rules of
+// each field as constraints.
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := s.object(field.Type)
+			for key, value := range embedded.Properties {
+				schema.Properties[key] = value
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := s.of(field.Type)
+		if applyRules(property, field.Type, field.Tag.Get("binding")) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+	return schema
+}
+
+// applyRules adds the validator rules in tag to schema and reports whether
+// the field is required. Aliases such as "recent" are expanded first, and
+// rules after "dive" apply to the items of a slice.
+func applyRules(schema *Schema, t reflect.Type, tag string) bool {
+	if tag == "" || schema.Ref != "" {
+		return false
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	required := false
+	var notes []string
+	var rules []string
+	for _, rule := range strings.Split(tag, ",") {
+		rules = append(rules, strings.Split(validation.Expand(rule), ",")...)
+	}
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		limit, _ := strconv.Atoi(param)
+
+		switch name {
+		case "dive":
+			if schema.Items != nil {
+				applyRules(schema.Items, t.Elem(), strings.Join(rules[i+1:], ","))
+			}
+		case "required":
+			required = true
+		case "notblank":
+			if schema.MinLength == nil {
+				setLimit(schema, reflect.String, "min", 1)
+			}
+			notes = append(notes, "must not be blank")
+		case "min", "max":
+			setLimit(schema, t.Kind(), name, limit)
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "email":
+			schema.Format = "email"
+		case "timezone":
+			notes = append(notes, "an IANA time zone such as Europe/Berlin")
+		case "rfc3339":
+			notes = append(notes, "an RFC 3339 date or date-time")
+		case "maxyearsago":
+			notes = append(notes, "at most "+param+" years in the past")
+		case "required_without":
+			notes = append(notes, "required unless "+lowerFirst(param)+" is given")
+		case "excluded_with":
+			notes = append(notes, "not allowed together with "+lowerFirst(param))
+		}
+		if name == "dive" {
+			break
+		}
+	}
+	if len(notes) > 0 {
+		schema.Description = strings.Join(notes, "; ")
+	}
+	return required
+}
+
+func setLimit(schema *Schema, kind reflect.Kind, rule string, limit int) {
+	var target **int
+	switch {
+	case kind == reflect.String && rule == "min":
+		target = &schema.MinLength
+	case kind == reflect.String:
+		target = &schema.MaxLength
+	case (kind == reflect.Slice || kind == reflect.Array) && rule == "min":
+		target = &schema.MinItems
+	case kind == reflect.Slice || kind == reflect.Array:
+		target = &schema.MaxItems
+	case rule == "min":
+		target = &schema.Minimum
+	default:
+		target = &schema.Maximum
+	}
+	*target = &limit
+}
+
+func lowerFirst(name string) string {
+	if name == "" {
+		return name
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
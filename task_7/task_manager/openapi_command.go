@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"task_manager/config"
+	"task_manager/openapi"
+	"task_manager/router"
+
+	"github.com/gin-gonic/gin"
+)
+
+const openapiUsage = `usage: task_manager openapi print|check [flags]
+
+print writes the OpenAPI document to stdout. check exits with status 1 if a
+route is not described or a described operation has no route, so CI can
+catch the two drifting apart. Both accept the same flags as the server; the
+SSO routes are only present when OIDC is configured.`
+
+// openapiCommand runs "task_manager openapi ...". The router is built
+// without a database connection; only its routes are used.
+func openapiCommand(args []string) int {
+	if len(args) == 0 || (args[0] != "print" && args[0] != "check") {
+		fmt.Fprintln(os.Stderr, openapiUsage)
+		return 2
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 2
+	}
+
+	gin.SetMode(gin.ReleaseMode)
+	gin.DefaultWriter = io.Discard
+	routes := router.SetupRouter(cfg).Routes()
+
+	if args[0] == "check" {
+		if err := openapi.Check(routes); err != nil {
+			fmt.Fprintf(os.Stderr, "OpenAPI document does not match the routes:\n%v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	encoded, err := json.MarshalIndent(openapi.Build(routes), "", "  ")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	fmt.Println(string(encoded))
+	return 0
+}
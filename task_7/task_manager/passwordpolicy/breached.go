@@ -0,0 +1,193 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	prefixLength = 5
+	hashLength   = sha1.Size * 2
+	// sampleLines is how many lines spread over a sorted file are checked
+	// for order when it is opened.
+	sampleLines = 64
+)
+
+// BreachedList looks up SHA-1 hashes of known breached passwords on disk, so
+// a list the size of Have I Been Pwned's needs neither memory nor loading
+// time. It is either one file sorted by hash, which is bisected, or a
+// directory of files named after the first five hex characters of the hashes
+// they hold, the layout of the HIBP k-anonymity range API; then only one
+// small file is read per lookup.
+//
+// Lines hold a hex hash, without the prefix in the directory layout, and
+// optionally ":count" as in the HIBP downloads.
+type BreachedList struct {
+	dir string
+	// file and size are set for a single sorted file.
+	file *os.File
+	size int64
+}
+
+// OpenBreachedList opens the file or directory at path and checks that it
+// looks like a breached password list.
+func OpenBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+
+	if info.IsDir() {
+		list := &BreachedList{dir: path}
+		// Any lookup reads a range file, so a wrong directory shows up now
+		// rather than as missing files on every password change.
+		if _, err := list.lookup(strings.Repeat("0", hashLength)); err != nil {
+			return nil, err
+		}
+		return list, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open breached password list: %w", err)
+	}
+	list := &BreachedList{file: file, size: info.Size()}
+	if err := list.checkSorted(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("breached password list %s: %w", path, err)
+	}
+	return list, nil
+}
+
+func (l *BreachedList) Close() error {
+	if l.file == nil {
+		return nil
+	}
+	return l.file.Close()
+}
+
+// Contains reports whether the password's hash is on the list.
+func (l *BreachedList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	return l.lookup(strings.ToUpper(hex.EncodeToString(sum[:])))
+}
+
+func (l *BreachedList) lookup(hash string) (bool, error) {
+	if l.file != nil {
+		return l.search(hash)
+	}
+
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+	file, err := os.Open(filepath.Join(l.dir, prefix+".txt"))
+	if err != nil {
+		return false, fmt.Errorf("open breached password range: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if lineHash(scanner.Bytes()) == suffix {
+			return true, nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read breached password range %s: %w", prefix, err)
+	}
+	return false, nil
+}
+
+// search bisects the sorted file by byte offset. low and high always sit
+// at line starts; each probe compares the first line starting at or after
+// the middle, or the line at low once no line starts between the middle
+// and high.
+func (l *BreachedList) search(hash string) (bool, error) {
+	low, high := int64(0), l.size
+	for low < high {
+		start, err := l.lineStart(low + (high-low)/2)
+		if err != nil {
+			return false, err
+		}
+		if start >= high {
+			start = low
+		}
+
+		line, next, err := l.lineAt(start)
+		if err != nil {
+			return false, err
+		}
+		switch found := lineHash(line); {
+		case found == hash:
+			return true, nil
+		case found < hash:
+			low = next
+		case start == low:
+			return false, nil
+		default:
+			high = start
+		}
+	}
+	return false, nil
+}
+
+// lineStart returns the offset of the first line starting at or after
+// offset, or the file size if there is none.
+func (l *BreachedList) lineStart(offset int64) (int64, error) {
+	if offset == 0 {
+		return 0, nil
+	}
+	_, next, err := l.lineAt(offset - 1)
+	return next, err
+}
+
+// lineAt reads from offset to the end of the line, without the line ending,
+// and returns the offset after it.
+func (l *BreachedList) lineAt(offset int64) ([]byte, int64, error) {
+	reader := bufio.NewReaderSize(io.NewSectionReader(l.file, offset, l.size-offset), 256)
+	line, err := reader.ReadSlice('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, 0, fmt.Errorf("read breached password list: %w", err)
+	}
+	next := offset + int64(len(line))
+	return bytes.TrimRight(line, "\r\n"), next, nil
+}
+
+// checkSorted reads lines spread over the file and fails if one is not a
+// hash or they are out of order, which would make lookups miss.
+func (l *BreachedList) checkSorted() error {
+	previous := ""
+	for i := range int64(sampleLines) {
+		start, err := l.lineStart(l.size * i / sampleLines)
+		if err != nil {
+			return err
+		}
+		line, _, err := l.lineAt(start)
+		if err != nil {
+			return err
+		}
+		if len(line) == 0 {
+			continue
+		}
+		hash := lineHash(line)
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != hashLength {
+			return fmt.Errorf("line %q is not a SHA-1 hash", line)
+		}
+		if hash < previous {
+			return errors.New("not sorted by hash")
+		}
+		previous = hash
+	}
+	return nil
+}
+
+func lineHash(line []byte) string {
+	hash, _, _ := bytes.Cut(line, []byte(":"))
+	return strings.ToUpper(string(bytes.TrimSpace(hash)))
+}
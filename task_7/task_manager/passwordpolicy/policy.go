@@ -0,0 +1,116 @@
+// Package passwordpolicy decides whether a new password is acceptable. It is
+// applied wherever a password is set: registration, invitation acceptance,
+// password change and password reset.
+package passwordpolicy
+
+import (
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"task_manager/config"
+)
+
+// MaxBytes is bcrypt's input limit. Longer passwords would be silently
+// truncated, so they are rejected instead.
+const MaxBytes = 72
+
+type Policy struct {
+	MinLength int
+	// MinClasses is how many of lowercase, uppercase, digits and symbols
+	// must appear.
+	MinClasses int
+	// Breached is consulted last; nil disables the check.
+	Breached *BreachedList
+}
+
+// New builds the policy from cfg, opening the breached password list if
+// one is configured.
+func New(cfg config.Password) (*Policy, error) {
+	policy := &Policy{MinLength: cfg.MinLength, MinClasses: cfg.MinClasses}
+
+	if cfg.BreachedFile != "" {
+		breached, err := OpenBreachedList(cfg.BreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = breached
+	}
+
+	return policy, nil
+}
+
+// Rules a password can break, as reported in Violation.Rule.
+const (
+	RuleTooShort         = "too_short"
+	RuleTooLong          = "too_long"
+	RuleTooFewClasses    = "too_few_classes"
+	RuleContainsUsername = "contains_username"
+	RuleBreached         = "breached"
+)
+
+// Violation is one rule a password breaks. Limit is the configured bound for
+// rules that have one. The API describes it to the user in their language.
+type Violation struct {
+	Rule  string
+	Limit int
+}
+
+// Validate returns every rule the password breaks, or nil if it is
+// acceptable.
+func (p *Policy) Validate(username, password string) []Violation {
+	var violations []Violation
+
+	if length := len([]rune(password)); length < p.MinLength {
+		violations = append(violations, Violation{Rule: RuleTooShort, Limit: p.MinLength})
+	}
+
+	if len(password) > MaxBytes {
+		violations = append(violations, Violation{Rule: RuleTooLong, Limit: MaxBytes})
+	}
+
+	if classes := countClasses(password); classes < p.MinClasses {
+		violations = append(violations, Violation{Rule: RuleTooFewClasses, Limit: p.MinClasses})
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		violations = append(violations, Violation{Rule: RuleContainsUsername})
+	}
+
+	if p.Breached != nil {
+		// A list that cannot be read should not stop everyone from
+		// setting a password, so the check is skipped and logged.
+		breached, err := p.Breached.Contains(password)
+		if err != nil {
+			slog.Error("breached password lookup failed", "error", err)
+		} else if breached {
+			violations = append(violations, Violation{Rule: RuleBreached})
+		}
+	}
+
+	return violations
+}
+
+func countClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,89 @@
+package problem
+
+// Code identifies a kind of problem. Codes are part of the API: clients
+// branch on them, so a code must never be renamed or reused for something
+// else. Each one needs a title in every catalog in messages.go.
+type Code string
+
+// Malformed requests.
+const (
+	CodeInvalidRequest   Code = "invalid_request"
+	CodeValidationFailed Code = "validation_failed"
+	CodeInvalidParameter Code = "invalid_parameter"
+	CodeMissingParameter Code = "missing_parameter"
+	CodeRouteNotFound    Code = "route_not_found"
+	CodeWeakPassword     Code = "weak_password"
+)
+
+// Authentication and authorization.
+const (
+	CodeAuthorizationRequired      Code = "authorization_required"
+	CodeInvalidAuthorizationHeader Code = "invalid_authorization_header"
+	CodeInvalidToken               Code = "invalid_token"
+	CodeSessionExpired             Code = "session_expired"
+	CodeAccountDisabled            Code = "account_disabled"
+	CodeInvalidCredentials         Code = "invalid_credentials"
+	CodeInvalidChallenge           Code = "invalid_challenge"
+	CodeInvalidCode                Code = "invalid_code"
+	CodeInvalidInvitation          Code = "invalid_invitation"
+	CodeTooManyAttempts            Code = "too_many_attempts"
+	CodeRateLimited                Code = "rate_limited"
+	CodeRegistrationClosed         Code = "registration_closed"
+	CodeAdminRequired              Code = "admin_required"
+	CodeWorkspaceAdminRequired     Code = "workspace_admin_required"
+	CodeInsufficientScope          Code = "insufficient_scope"
+	CodeAPITokenNotAllowed         Code = "api_token_not_allowed"
+	CodeTwoFactorRequired          Code = "two_factor_required"
+	CodeAdminScopeNotAllowed       Code = "admin_scope_not_allowed"
+	CodeSSOFailed                  Code = "sso_failed"
+	CodeSSONotVerified             Code = "sso_not_verified"
+	CodeInvalidSSOState            Code = "invalid_sso_state"
+	CodeSSOAccountNotLinked        Code = "sso_account_not_linked"
+	CodeSSOLinkRequired            Code = "sso_link_required"
+	CodeReauthenticationRequired   Code = "reauthentication_required"
+)
+
+// Missing records. The data layer reports these as "<thing>_not_found".
+const (
+	CodeUserNotFound            Code = "user_not_found"
+	CodeTaskNotFound            Code = "task_not_found"
+	CodeWorkspaceNotFound       Code = "workspace_not_found"
+	CodeWorkspaceMemberNotFound Code = "workspace_member_not_found"
+	CodeInvitationNotFound      Code = "invitation_not_found"
+	CodeSessionNotFound         Code = "session_not_found"
+	CodeTokenNotFound           Code = "token_not_found"
+	CodeServiceAccountNotFound  Code = "service_account_not_found"
+	CodeLockoutNotFound         Code = "lockout_not_found"
+	// CodeNotFound is a missing record the data layer could not name.
+	CodeNotFound Code = "not_found"
+)
+
+// Conflicts with the current state.
+const (
+	CodeUsernameTaken           Code = "username_taken"
+	CodeInvitationPending       Code = "invitation_pending"
+	CodeInvitationUsed          Code = "invitation_used"
+	CodeAlreadyWorkspaceMember  Code = "already_workspace_member"
+	CodeEmailChanged            Code = "email_changed"
+	CodeEmailAlreadyVerified    Code = "email_already_verified"
+	CodeNoEmailAddress          Code = "no_email_address"
+	CodeCodeAlreadyUsed         Code = "code_already_used"
+	CodeNoPendingEnrollment     Code = "no_pending_enrollment"
+	CodeTwoFactorAlreadyEnabled Code = "two_factor_already_enabled"
+	CodeTwoFactorNotEnabled     Code = "two_factor_not_enabled"
+	CodeEnableTwoFactorFirst    Code = "enable_two_factor_first"
+	CodeLastAdmin               Code = "last_admin"
+	CodeLastWorkspaceAdmin      Code = "last_workspace_admin"
+	CodeReassignToDeletedUser   Code = "reassign_to_deleted_user"
+	CodeReassignToNonMember     Code = "reassign_to_non_member"
+	CodeSSOIdentityInUse        Code = "sso_identity_in_use"
+	// CodeConflict is a clash the data layer could not name, such as a
+	// duplicate key.
+	CodeConflict Code = "conflict"
+)
+
+// Server-side failures.
+const (
+	CodeUnavailable Code = "service_unavailable"
+	CodeInternal    Code = "internal_error"
+)
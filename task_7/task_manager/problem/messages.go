@@ -0,0 +1,305 @@
+package problem
+
+import (
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// messages is the text of every problem in one language. Details and field
+// messages may hold {placeholders}: a detail takes the problem's Params, a
+// field message takes {param} from its FieldError.
+type messages struct {
+	titles  map[Code]string
+	details map[Code]string
+	// fields is keyed by rule, or by "rule.kind" where the wording depends
+	// on whether characters or items are counted. "invalid" is the
+	// fallback for rules without a message.
+	fields map[string]string
+}
+
+// supported lists the languages with a catalog. The first is the default.
+var supported = []language.Tag{language.English, language.German}
+
+var matcher = language.NewMatcher(supported)
+
+// Negotiate picks the supported language that best fits an Accept-Language
+// header.
+func Negotiate(header string) language.Tag {
+	tags, _, _ := language.ParseAcceptLanguage(header)
+	_, index, _ := matcher.Match(tags...)
+	return supported[index]
+}
+
+func (m messages) title(code Code) string {
+	if title, ok := m.titles[code]; ok {
+		return title
+	}
+	if title, ok := catalogs[language.English].titles[code]; ok {
+		return title
+	}
+	return string(code)
+}
+
+func (m messages) detail(code Code, params map[string]string) string {
+	detail, ok := m.details[code]
+	if !ok {
+		detail = catalogs[language.English].details[code]
+	}
+	return fill(detail, params)
+}
+
+func (m messages) field(fieldErr FieldError) string {
+	message, ok := m.fields[fieldErr.Code+"."+fieldErr.kind]
+	if !ok {
+		message, ok = m.fields[fieldErr.Code]
+	}
+	if !ok {
+		message = m.fields["invalid"]
+	}
+	return fill(message, map[string]string{"param": fieldErr.Param})
+}
+
+func fill(text string, params map[string]string) string {
+	for key, value := range params {
+		text = strings.ReplaceAll(text, "{"+key+"}", value)
+	}
+	return text
+}
+
+var catalogs = map[language.Tag]messages{
+	language.English: {
+		titles: map[Code]string{
+			CodeInvalidRequest:   "Malformed request body",
+			CodeValidationFailed: "Request validation failed",
+			CodeInvalidParameter: "Invalid parameter",
+			CodeMissingParameter: "Missing parameter",
+			CodeRouteNotFound:    "No such endpoint",
+			CodeWeakPassword:     "Password does not meet the policy",
+
+			CodeAuthorizationRequired:      "Authorization required",
+			CodeInvalidAuthorizationHeader: "Invalid authorization header",
+			CodeInvalidToken:               "Invalid or expired token",
+			CodeSessionExpired:             "Session expired",
+			CodeAccountDisabled:            "Account disabled",
+			CodeInvalidCredentials:         "Invalid credentials",
+			CodeInvalidChallenge:           "Invalid or expired challenge",
+			CodeInvalidCode:                "Invalid code",
+			CodeInvalidInvitation:          "Invalid or expired invitation",
+			CodeTooManyAttempts:            "Too many failed attempts",
+			CodeRateLimited:                "Too many requests",
+			CodeRegistrationClosed:         "Registration is by invitation only",
+			CodeAdminRequired:              "Admin access required",
+			CodeWorkspaceAdminRequired:     "Workspace admin access required",
+			CodeInsufficientScope:          "Insufficient token scope",
+			CodeAPITokenNotAllowed:         "API tokens not allowed",
+			CodeTwoFactorRequired:          "Two-factor authentication required",
+			CodeAdminScopeNotAllowed:       "Admin scope not allowed",
+			CodeSSOFailed:                  "Sign-in failed at the identity provider",
+			CodeSSONotVerified:             "Sign-in could not be verified",
+			CodeInvalidSSOState:            "Invalid sign-in state",
+			CodeSSOAccountNotLinked:        "No account for this sign-in",
+			CodeSSOLinkRequired:            "Sign in to link this identity",
+			CodeReauthenticationRequired:   "Sign in again to confirm",
+
+			CodeUserNotFound:            "User not found",
+			CodeTaskNotFound:            "Task not found",
+			CodeWorkspaceNotFound:       "Workspace not found",
+			CodeWorkspaceMemberNotFound: "Workspace member not found",
+			CodeInvitationNotFound:      "Invitation not found",
+			CodeSessionNotFound:         "Session not found",
+			CodeTokenNotFound:           "Token not found",
+			CodeServiceAccountNotFound:  "Service account not found",
+			CodeLockoutNotFound:         "No failed logins recorded",
+			CodeNotFound:                "Not found",
+
+			CodeUsernameTaken:           "Username already exists",
+			CodeInvitationPending:       "Pending invitation already exists",
+			CodeInvitationUsed:          "Invitation is no longer valid",
+			CodeAlreadyWorkspaceMember:  "Already a workspace member",
+			CodeEmailChanged:            "Email address has changed",
+			CodeEmailAlreadyVerified:    "Email address already verified",
+			CodeNoEmailAddress:          "No email address on the account",
+			CodeCodeAlreadyUsed:         "Code already used",
+			CodeNoPendingEnrollment:     "No pending enrollment",
+			CodeTwoFactorAlreadyEnabled: "Two-factor authentication already enabled",
+			CodeTwoFactorNotEnabled:     "Two-factor authentication not enabled",
+			CodeEnableTwoFactorFirst:    "Two-factor authentication required on your account",
+			CodeLastAdmin:               "Cannot remove the last admin",
+			CodeLastWorkspaceAdmin:      "Cannot remove the last workspace admin",
+			CodeReassignToDeletedUser:   "Cannot reassign to the deleted user",
+			CodeReassignToNonMember:     "Cannot reassign to a non-member",
+			CodeSSOIdentityInUse:        "Identity linked to another account",
+			CodeConflict:                "Conflict with the current state",
+
+			CodeUnavailable: "Service temporarily unavailable",
+			CodeInternal:    "Internal server error",
+		},
+		details: map[Code]string{
+			CodeInvalidRequest:             "The request body is not valid JSON or does not match the expected shape.",
+			CodeValidationFailed:           "One or more fields are invalid; see errors.",
+			CodeInvalidParameter:           "The {name} parameter is not valid.",
+			CodeMissingParameter:           "The {name} parameter is required.",
+			CodeWeakPassword:               "The password was rejected; see errors.",
+			CodeTooManyAttempts:            "Try again later.",
+			CodeRateLimited:                "Try again in {seconds} seconds.",
+			CodeInsufficientScope:          "The token is missing the {scope} scope.",
+			CodeAPITokenNotAllowed:         "This endpoint cannot be used with an API token.",
+			CodeTwoFactorRequired:          "Admins must enable two-factor authentication.",
+			CodeAdminScopeNotAllowed:       "Only admins can hold the admin scope.",
+			CodeEnableTwoFactorFirst:       "Enable two-factor authentication on your own account first.",
+			CodeReassignToDeletedUser:      "Tasks cannot be reassigned to the user being deleted.",
+			CodeReassignToNonMember:        "The user in reassignTo is not a member of the workspace holding some of the tasks.",
+			CodeSSOLinkRequired:            "This account can only be linked from a signed-in session: sign in with your password, then link the identity provider under /me/sso/link.",
+			CodeReauthenticationRequired:   "The account has no password. Sign in with the identity provider again and retry within {minutes} minutes.",
+			CodeUnavailable:                "Try again in a few seconds.",
+			CodeInvalidAuthorizationHeader: "Expected \"Bearer <token>\".",
+		},
+		fields: map[string]string{
+			"required":         "is required",
+			"notblank":         "must not be blank",
+			"rfc3339":          "must be an RFC 3339 date such as 2025-12-31 or 2025-12-31T17:00:00Z",
+			"recent":           "must not be more than {param} years in the past",
+			"required_without": "is required unless {param} is given",
+			"excluded_with":    "must not be given together with {param}",
+			"email":            "must be an email address",
+			"oneof":            "must be one of: {param}",
+			"timezone":         "must be an IANA time zone such as Europe/Berlin",
+			"max":              "must be at most {param}",
+			"max.string":       "must be at most {param} characters long",
+			"max.slice":        "must have at most {param} items",
+			"min":              "must be at least {param}",
+			"min.string":       "must be at least {param} characters long",
+			"min.slice":        "must have at least {param} items",
+			"type.string":      "must be a string",
+			"type.number":      "must be a number",
+			"type.bool":        "must be true or false",
+			"type.slice":       "must be an array",
+			"type.object":      "must be an object",
+			"invalid":          "is invalid",
+
+			"too_short":         "must be at least {param} characters long",
+			"too_long":          "must be at most {param} bytes long",
+			"too_few_classes":   "must mix at least {param} of lowercase, uppercase, digits and symbols",
+			"contains_username": "must not contain the username",
+			"breached":          "appears in a list of breached passwords",
+		},
+	},
+	language.German: {
+		titles: map[Code]string{
+			CodeInvalidRequest:   "Ungültiger Anfrageinhalt",
+			CodeValidationFailed: "Validierung der Anfrage fehlgeschlagen",
+			CodeInvalidParameter: "Ungültiger Parameter",
+			CodeMissingParameter: "Fehlender Parameter",
+			CodeRouteNotFound:    "Endpunkt nicht gefunden",
+			CodeWeakPassword:     "Passwort entspricht nicht den Richtlinien",
+
+			CodeAuthorizationRequired:      "Autorisierung erforderlich",
+			CodeInvalidAuthorizationHeader: "Ungültiger Authorization-Header",
+			CodeInvalidToken:               "Ungültiges oder abgelaufenes Token",
+			CodeSessionExpired:             "Sitzung abgelaufen",
+			CodeAccountDisabled:            "Konto deaktiviert",
+			CodeInvalidCredentials:         "Ungültige Anmeldedaten",
+			CodeInvalidChallenge:           "Ungültige oder abgelaufene Anmeldeanforderung",
+			CodeInvalidCode:                "Ungültiger Code",
+			CodeInvalidInvitation:          "Ungültige oder abgelaufene Einladung",
+			CodeTooManyAttempts:            "Zu viele fehlgeschlagene Versuche",
+			CodeRateLimited:                "Zu viele Anfragen",
+			CodeRegistrationClosed:         "Registrierung nur mit Einladung",
+			CodeAdminRequired:              "Administratorrechte erforderlich",
+			CodeWorkspaceAdminRequired:     "Arbeitsbereich-Administratorrechte erforderlich",
+			CodeInsufficientScope:          "Unzureichender Token-Geltungsbereich",
+			CodeAPITokenNotAllowed:         "API-Tokens nicht erlaubt",
+			CodeTwoFactorRequired:          "Zwei-Faktor-Authentifizierung erforderlich",
+			CodeAdminScopeNotAllowed:       "Admin-Geltungsbereich nicht erlaubt",
+			CodeSSOFailed:                  "Anmeldung beim Identitätsanbieter fehlgeschlagen",
+			CodeSSONotVerified:             "Anmeldung konnte nicht überprüft werden",
+			CodeInvalidSSOState:            "Ungültiger Anmeldestatus",
+			CodeSSOAccountNotLinked:        "Kein Konto für diese Anmeldung",
+			CodeSSOLinkRequired:            "Zum Verknüpfen dieser Identität anmelden",
+			CodeReauthenticationRequired:   "Zur Bestätigung erneut anmelden",
+
+			CodeUserNotFound:            "Benutzer nicht gefunden",
+			CodeTaskNotFound:            "Aufgabe nicht gefunden",
+			CodeWorkspaceNotFound:       "Arbeitsbereich nicht gefunden",
+			CodeWorkspaceMemberNotFound: "Mitglied des Arbeitsbereichs nicht gefunden",
+			CodeInvitationNotFound:      "Einladung nicht gefunden",
+			CodeSessionNotFound:         "Sitzung nicht gefunden",
+			CodeTokenNotFound:           "Token nicht gefunden",
+			CodeServiceAccountNotFound:  "Dienstkonto nicht gefunden",
+			CodeLockoutNotFound:         "Keine fehlgeschlagenen Anmeldungen erfasst",
+			CodeNotFound:                "Nicht gefunden",
+
+			CodeUsernameTaken:           "Benutzername bereits vergeben",
+			CodeInvitationPending:       "Es gibt bereits eine offene Einladung",
+			CodeInvitationUsed:          "Einladung ist nicht mehr gültig",
+			CodeAlreadyWorkspaceMember:  "Bereits Mitglied des Arbeitsbereichs",
+			CodeEmailChanged:            "E-Mail-Adresse wurde geändert",
+			CodeEmailAlreadyVerified:    "E-Mail-Adresse bereits bestätigt",
+			CodeNoEmailAddress:          "Keine E-Mail-Adresse im Konto hinterlegt",
+			CodeCodeAlreadyUsed:         "Code bereits verwendet",
+			CodeNoPendingEnrollment:     "Keine ausstehende Einrichtung",
+			CodeTwoFactorAlreadyEnabled: "Zwei-Faktor-Authentifizierung bereits aktiviert",
+			CodeTwoFactorNotEnabled:     "Zwei-Faktor-Authentifizierung nicht aktiviert",
+			CodeEnableTwoFactorFirst:    "Zwei-Faktor-Authentifizierung für Ihr Konto erforderlich",
+			CodeLastAdmin:               "Der letzte Administrator kann nicht entfernt werden",
+			CodeLastWorkspaceAdmin:      "Der letzte Administrator des Arbeitsbereichs kann nicht entfernt werden",
+			CodeReassignToDeletedUser:   "Übertragung an den gelöschten Benutzer nicht möglich",
+			CodeReassignToNonMember:     "Übertragung an ein Nichtmitglied nicht möglich",
+			CodeSSOIdentityInUse:        "Identität ist mit einem anderen Konto verknüpft",
+			CodeConflict:                "Konflikt mit dem aktuellen Zustand",
+
+			CodeUnavailable: "Dienst vorübergehend nicht verfügbar",
+			CodeInternal:    "Interner Serverfehler",
+		},
+		details: map[Code]string{
+			CodeInvalidRequest:             "Der Anfrageinhalt ist kein gültiges JSON oder hat nicht die erwartete Form.",
+			CodeValidationFailed:           "Mindestens ein Feld ist ungültig; siehe errors.",
+			CodeInvalidParameter:           "Der Parameter {name} ist ungültig.",
+			CodeMissingParameter:           "Der Parameter {name} ist erforderlich.",
+			CodeWeakPassword:               "Das Passwort wurde abgelehnt; siehe errors.",
+			CodeTooManyAttempts:            "Bitte versuchen Sie es später erneut.",
+			CodeRateLimited:                "Bitte versuchen Sie es in {seconds} Sekunden erneut.",
+			CodeInsufficientScope:          "Dem Token fehlt der Geltungsbereich {scope}.",
+			CodeAPITokenNotAllowed:         "Dieser Endpunkt kann nicht mit einem API-Token verwendet werden.",
+			CodeTwoFactorRequired:          "Administratoren müssen die Zwei-Faktor-Authentifizierung aktivieren.",
+			CodeAdminScopeNotAllowed:       "Nur Administratoren können den Admin-Geltungsbereich erhalten.",
+			CodeEnableTwoFactorFirst:       "Aktivieren Sie zuerst die Zwei-Faktor-Authentifizierung für Ihr eigenes Konto.",
+			CodeReassignToDeletedUser:      "Aufgaben können nicht dem zu löschenden Benutzer übertragen werden.",
+			CodeReassignToNonMember:        "Der Benutzer in reassignTo ist kein Mitglied des Arbeitsbereichs, zu dem einige der Aufgaben gehören.",
+			CodeSSOLinkRequired:            "Dieses Konto kann nur aus einer angemeldeten Sitzung verknüpft werden: Melden Sie sich mit Ihrem Passwort an und verknüpfen Sie den Identitätsanbieter unter /me/sso/link.",
+			CodeReauthenticationRequired:   "Das Konto hat kein Passwort. Melden Sie sich erneut beim Identitätsanbieter an und wiederholen Sie den Vorgang innerhalb von {minutes} Minuten.",
+			CodeUnavailable:                "Bitte versuchen Sie es in einigen Sekunden erneut.",
+			CodeInvalidAuthorizationHeader: "Erwartet wird \"Bearer <token>\".",
+		},
+		fields: map[string]string{
+			"required":         "ist erforderlich",
+			"notblank":         "darf nicht leer sein",
+			"rfc3339":          "muss ein RFC-3339-Datum wie 2025-12-31 oder 2025-12-31T17:00:00Z sein",
+			"recent":           "darf nicht mehr als {param} Jahre zurückliegen",
+			"required_without": "ist erforderlich, sofern {param} fehlt",
+			"excluded_with":    "darf nicht zusammen mit {param} angegeben werden",
+			"email":            "muss eine E-Mail-Adresse sein",
+			"oneof":            "muss einer der Werte {param} sein",
+			"timezone":         "muss eine IANA-Zeitzone wie Europe/Berlin sein",
+			"max":              "darf höchstens {param} sein",
+			"max.string":       "darf höchstens {param} Zeichen lang sein",
+			"max.slice":        "darf höchstens {param} Einträge haben",
+			"min":              "muss mindestens {param} sein",
+			"min.string":       "muss mindestens {param} Zeichen lang sein",
+			"min.slice":        "muss mindestens {param} Einträge haben",
+			"type.string":      "muss eine Zeichenkette sein",
+			"type.number":      "muss eine Zahl sein",
+			"type.bool":        "muss true oder false sein",
+			"type.slice":       "muss ein Array sein",
+			"type.object":      "muss ein Objekt sein",
+			"invalid":          "ist ungültig",
+
+			"too_short":         "muss mindestens {param} Zeichen lang sein",
+			"too_long":          "darf höchstens {param} Bytes lang sein",
+			"too_few_classes":   "muss mindestens {param} der Klassen Kleinbuchstaben, Großbuchstaben, Ziffern und Sonderzeichen enthalten",
+			"contains_username": "darf den Benutzernamen nicht enthalten",
+			"breached":          "steht auf einer Liste kompromittierter Passwörter",
+		},
+	},
+}
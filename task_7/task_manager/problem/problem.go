@@ -0,0 +1,144 @@
+// Package problem describes API errors as RFC 7807 problem details, served
+// as application/problem+json. Every problem carries a stable code that
+// clients can branch on; its title and detail are translated into the
+// language the client asks for with Accept-Language.
+package problem
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// typePrefix turns a code into the problem's type URI. A URN needs no
+// documentation server to resolve it.
+const typePrefix = "urn:task-manager:problem:"
+
+// Problem is an error meant for an API client. Build one with New, then
+// hand it to Abort; the Problems middleware renders it.
+type Problem struct {
+	Status int
+	Code   Code
+	// Params fill the {placeholders} in the code's detail message.
+	Params map[string]string
+	// Errors lists the invalid fields of a request body.
+	Errors []FieldError
+	// Extensions are added to the response as extra members.
+	Extensions map[string]any
+	// Cause is logged but never sent to the client.
+	Cause error
+}
+
+// FieldError is one invalid field. Code is the rule the field broke, such
+// as a validator tag like "required"; Param is that rule's argument.
+type FieldError struct {
+	Field string
+	Code  string
+	Param string
+	// kind is "string" or "slice" when the rule counts characters or
+	// items, which the message has to say.
+	kind string
+}
+
+func New(status int, code Code) *Problem {
+	return &Problem{Status: status, Code: code}
+}
+
+// With sets the detail placeholder key.
+func (p *Problem) With(key, value string) *Problem {
+	if p.Params == nil {
+		p.Params = map[string]string{}
+	}
+	p.Params[key] = value
+	return p
+}
+
+// Extend adds an extension member to the response.
+func (p *Problem) Extend(key string, value any) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]any{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+func (p *Problem) WithCause(err error) *Problem {
+	p.Cause = err
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Cause != nil {
+		return fmt.Sprintf("%s: %v", p.Code, p.Cause)
+	}
+	return string(p.Code)
+}
+
+func (p *Problem) Unwrap() error {
+	return p.Cause
+}
+
+// InvalidParameter reports a path or query parameter that could not be
+// used.
+func InvalidParameter(name string) *Problem {
+	return New(http.StatusBadRequest, CodeInvalidParameter).With("name", name)
+}
+
+// Abort records err for the Problems middleware and stops the handler
+// chain. err is usually a *Problem; anything else is mapped by the
+// middleware.
+func Abort(c *gin.Context, err error) {
+	_ = c.Error(err)
+	c.Abort()
+}
+
+// Write renders p in the language negotiated from the request.
+func Write(c *gin.Context, p *Problem) {
+	lang := Negotiate(c.GetHeader("Accept-Language"))
+	messages := catalogs[lang]
+
+	body := map[string]any{
+		"type":     typePrefix + string(p.Code),
+		"title":    messages.title(p.Code),
+		"status":   p.Status,
+		"code":     p.Code,
+		"instance": c.Request.URL.Path,
+	}
+	if detail := messages.detail(p.Code, p.Params); detail != "" {
+		body["detail"] = detail
+	}
+	if requestID := c.GetString("request_id"); requestID != "" {
+		body["requestId"] = requestID
+	}
+	if len(p.Errors) > 0 {
+		fields := make([]map[string]string, len(p.Errors))
+		for i, fieldErr := range p.Errors {
+			fields[i] = map[string]string{
+				"field":   fieldErr.Field,
+				"code":    fieldErr.Code,
+				"message": messages.field(fieldErr),
+			}
+		}
+		body["errors"] = fields
+	}
+	for key, value := range p.Extensions {
+		if _, reserved := body[key]; !reserved {
+			body[key] = value
+		}
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		encoded = []byte(`{"type":"` + typePrefix + string(CodeInternal) + `","status":500}`)
+		p.Status = http.StatusInternalServerError
+	}
+
+	c.Header("Content-Language", lang.String())
+	c.Header("Vary", strings.TrimPrefix(c.Writer.Header().Get("Vary")+", Accept-Language", ", "))
+	c.Data(p.Status, ContentType, encoded)
+}
@@ -1,46 +1,211 @@
 package router
 
 import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"task_manager/config"
 	"task_manager/controllers"
+	database "task_manager/data"
+	"task_manager/mailer"
+	"task_manager/metrics"
 	"task_manager/middleware"
+	"task_manager/openapi"
+	"task_manager/passwordpolicy"
+	"task_manager/problem"
+	"task_manager/ratelimit"
+	"task_manager/sso"
+	"task_manager/tracing"
+	"task_manager/validation"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-func SetupRouter() *gin.Engine {
+// SetupRouter builds the API from cfg. The database must already be
+// connected.
+func SetupRouter(cfg *config.Config) *gin.Engine {
+	m, err := mailer.New(cfg.Mail)
+	if err != nil {
+		slog.Error("failed to configure mailer", "error", err)
+		os.Exit(1)
+	}
+
+	policy, err := passwordpolicy.New(cfg.Password)
+	if err != nil {
+		slog.Error("failed to configure password policy", "error", err)
+		os.Exit(1)
+	}
+
+	var ssoClient *sso.Client
+	if cfg.OIDC.Enabled() {
+		ssoClient, err = sso.NewClient(context.Background(), cfg.OIDC)
+		if err != nil {
+			slog.Error("failed to configure OIDC", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	middleware.SetJWTSecret(cfg.Auth.JWTSecret)
+
 	taskController := controllers.NewTaskController()
-	authController := controllers.NewAuthController()
-	r := gin.Default()
+	authController := controllers.NewAuthController(cfg.Auth, cfg.Login, m, policy, ssoClient)
+	workspaceController := controllers.NewWorkspaceController()
+	invitationController := controllers.NewInvitationController(m)
+	userController := controllers.NewUserController()
+	accountController := controllers.NewAccountController(m, policy, cfg.Login)
+	healthController := controllers.NewHealthController()
+	if err := validation.Register(cfg.Tasks.DueDateYearsBack); err != nil {
+		slog.Error("failed to register validation rules", "error", err)
+		os.Exit(1)
+	}
+	problem.UseJSONFieldNames()
+	r := gin.New()
+	r.Use(otelgin.Middleware(tracing.ServiceName, otelgin.WithFilter(notProbe)))
+	r.Use(middleware.RequestID(), middleware.RequestLogger(), middleware.Recovery(), metrics.Middleware(), middleware.Problems())
+	r.NoRoute(middleware.NoRoute)
+
+	r.GET("/healthz", healthController.Healthz)
+	r.GET("/readyz", healthController.Readyz)
+
+	metrics.RegisterTaskCounts(database.CountTasksByStatus)
+	r.GET("/metrics", metrics.Handler(cfg.Metrics.Token))
+
+	requireTasksRead := middleware.RequireScope(database.ScopeTasksRead)
+	requireTasksWrite := middleware.RequireScope(database.ScopeTasksWrite)
+	requireWorkspacesWrite := middleware.RequireScope(database.ScopeWorkspacesWrite)
+
+	// Login throttling is keyed on the client IP, so X-Forwarded-For is only
+	// honoured from the configured proxies.
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		slog.Error("invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
+
+	// Each route group has its own bucket per client. The limits leave room
+	// for interactive use and scripts that pace themselves; /auth, which is
+	// counted by IP, is the tightest.
+	rateLimits := ratelimit.NewMemoryStore()
+	limit := func(group *gin.RouterGroup, name string, l ratelimit.Limit) {
+		if cfg.RateLimit.Enabled {
+			group.Use(middleware.RateLimit(rateLimits, name, l))
+		}
+	}
 
 	auth := r.Group("/auth")
+	limit(auth, "auth", ratelimit.Limit{Requests: 30, Per: time.Minute, Burst: 10})
 	{
 		auth.POST("/register", authController.Register)
 		auth.POST("/login", authController.Login)
+		auth.POST("/login/2fa", authController.LoginTwoFactor)
+		auth.POST("/accept-invitation", authController.AcceptInvitation)
+		auth.POST("/forgot-password", authController.ForgotPassword)
+		auth.POST("/reset-password", authController.ResetPassword)
+		auth.POST("/verify-email", authController.VerifyEmail)
+	}
+
+	if ssoClient != nil {
+		auth.GET("/oidc/login", authController.SSOLogin)
+		auth.GET("/oidc/callback", authController.SSOCallback)
 	}
 
 	admin := r.Group("/admin")
+	limit(admin, "admin", ratelimit.Limit{Requests: 120, Per: time.Minute, Burst: 30})
 	admin.Use(middleware.AuthMiddleware())
 	admin.Use(middleware.AdminMiddleware())
+	admin.Use(middleware.RequireScope(database.ScopeAdmin))
 	{
 		admin.POST("/promote", authController.Promote)
+		admin.GET("/users", userController.ListUsers)
+		admin.PATCH("/users/:id", userController.UpdateUser)
+		admin.DELETE("/users/:id", userController.DeleteUser)
+		admin.DELETE("/users/:id/sessions", userController.RevokeUserSessions)
+		admin.GET("/security-policy", userController.GetSecurityPolicy)
+		admin.PUT("/security-policy", userController.UpdateSecurityPolicy)
+		admin.GET("/lockouts", userController.GetLockouts)
+		admin.DELETE("/lockouts", userController.ClearLockout)
+		admin.GET("/audit", userController.ListAudit)
+		admin.GET("/audit/export", userController.ExportAudit)
+		admin.GET("/audit/verify", userController.VerifyAudit)
+		admin.POST("/invitations", invitationController.CreateInvitation)
+		admin.GET("/invitations", invitationController.GetInvitations)
+		admin.DELETE("/invitations/:id", invitationController.RevokeInvitation)
+		admin.POST("/service-accounts", userController.CreateServiceAccount)
+		admin.GET("/service-accounts", userController.ListServiceAccounts)
+		admin.POST("/service-accounts/:id/tokens", userController.CreateServiceAccountToken)
+		admin.GET("/service-accounts/:id/tokens", userController.ListServiceAccountTokens)
+		admin.DELETE("/service-accounts/:id/tokens/:tokenId", userController.RevokeServiceAccountToken)
+	}
+
+	me := r.Group("/me")
+	limit(me, "me", ratelimit.Limit{Requests: 120, Per: time.Minute, Burst: 30})
+	me.Use(middleware.AuthMiddleware())
+	me.Use(middleware.SessionOnly())
+	{
+		me.GET("", accountController.GetProfile)
+		me.PATCH("", accountController.UpdateProfile)
+		me.POST("/password", accountController.ChangePassword)
+		me.POST("/email/verification", accountController.ResendVerification)
+		me.POST("/2fa/enroll", accountController.EnrollTwoFactor)
+		me.POST("/2fa/confirm", accountController.ConfirmTwoFactor)
+		me.DELETE("/2fa", accountController.DisableTwoFactor)
+		me.GET("/export", accountController.ExportData)
+		me.DELETE("", accountController.DeleteAccount)
+		me.POST("/tokens", accountController.CreateToken)
+		me.GET("/tokens", accountController.ListTokens)
+		me.DELETE("/tokens/:tokenId", accountController.RevokeToken)
+		me.GET("/sessions", accountController.ListSessions)
+		me.DELETE("/sessions/:sessionId", accountController.RevokeSession)
+	}
+
+	if ssoClient != nil {
+		me.POST("/sso/link", authController.SSOLink)
 	}
 
-	tasks := r.Group("/tasks")
-	tasks.Use(middleware.AuthMiddleware())
+	workspaces := r.Group("/workspaces")
+	limit(workspaces, "workspaces", ratelimit.Limit{Requests: 300, Per: time.Minute, Burst: 60})
+	workspaces.Use(middleware.AuthMiddleware())
 	{
-		tasks.GET("", taskController.GetAllTasks)
-		tasks.GET("/:id", taskController.GetTask)
+		workspaces.POST("", requireWorkspacesWrite, workspaceController.CreateWorkspace)
+		workspaces.GET("", requireTasksRead, workspaceController.GetMyWorkspaces)
 	}
 
-	adminTasks := r.Group("/tasks")
-	adminTasks.Use(middleware.AuthMiddleware())
-	adminTasks.Use(middleware.AdminMiddleware())
+	workspace := workspaces.Group("/:ws")
+	workspace.Use(middleware.WorkspaceMiddleware())
 	{
-		adminTasks.POST("", taskController.CreateTask)
-		adminTasks.PUT("/:id", taskController.UpdateTask)
-		adminTasks.DELETE("/:id", taskController.DeleteTask)
+		workspace.GET("", requireTasksRead, workspaceController.GetWorkspace)
+		workspace.GET("/tasks", requireTasksRead, taskController.GetAllTasks)
+		workspace.GET("/tasks/:id", requireTasksRead, taskController.GetTask)
+	}
+
+	workspaceAdmin := workspace.Group("")
+	workspaceAdmin.Use(middleware.WorkspaceAdminMiddleware())
+	{
+		workspaceAdmin.POST("/members", requireWorkspacesWrite, workspaceController.AddMember)
+		workspaceAdmin.DELETE("/members/:userId", requireWorkspacesWrite, workspaceController.RemoveMember)
+		workspaceAdmin.POST("/invitations", requireWorkspacesWrite, invitationController.CreateWorkspaceInvitation)
+		workspaceAdmin.GET("/invitations", requireWorkspacesWrite, invitationController.GetWorkspaceInvitations)
+		workspaceAdmin.DELETE("/invitations/:id", requireWorkspacesWrite, invitationController.RevokeWorkspaceInvitation)
+		workspaceAdmin.POST("/tasks", requireTasksWrite, taskController.CreateTask)
+		workspaceAdmin.PUT("/tasks/:id", requireTasksWrite, taskController.UpdateTask)
+		workspaceAdmin.DELETE("/tasks/:id", requireTasksWrite, taskController.DeleteTask)
+	}
+
+	if err := openapi.Register(r); err != nil {
+		slog.Error("failed to build OpenAPI document", "error", err)
+		os.Exit(1)
+	}
+	if err := openapi.Check(r.Routes()); err != nil {
+		slog.Warn("OpenAPI document does not match the routes", "error", err)
 	}
 
 	return r
 }
 
+// notProbe keeps health checks, which arrive every few seconds, out of the
+// traces.
+func notProbe(req *http.Request) bool {
+	return req.URL.Path != "/healthz" && req.URL.Path != "/readyz"
+}
@@ -0,0 +1,130 @@
+// Package sso is the OpenID Connect relying party used to sign in with an
+// external identity provider. It performs the authorization code flow with
+// PKCE and turns a verified ID token into an Identity; mapping that identity
+// to a local account is left to the caller.
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+
+	"task_manager/config"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// Identity is what the identity provider asserts about the signed-in user.
+type Identity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	Groups        []string
+}
+
+type Client struct {
+	cfg      config.OIDC
+	oauth2   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewClient fetches the provider's discovery document, so the issuer must be
+// reachable.
+func NewClient(ctx context.Context, cfg config.OIDC) (*Client, error) {
+	provider, err := oidc.NewProvider(ctx, cfg.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("discover OIDC provider: %w", err)
+	}
+
+	return &Client{
+		cfg: cfg,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       cfg.Scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+	}, nil
+}
+
+// AuthCodeURL is where the browser is sent to sign in. verifier is the PKCE
+// code verifier, which must be kept until the callback.
+func (c *Client) AuthCodeURL(state, nonce, verifier string) string {
+	return c.oauth2.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier))
+}
+
+// Exchange redeems the authorization code, verifies the ID token's signature,
+// audience, expiry and nonce, and returns the identity it asserts.
+func (c *Client) Exchange(ctx context.Context, code, verifier, nonce string) (Identity, error) {
+	token, err := c.oauth2.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return Identity{}, fmt.Errorf("exchange code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		return Identity{}, errors.New("token response has no id_token")
+	}
+
+	idToken, err := c.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return Identity{}, fmt.Errorf("verify id_token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return Identity{}, errors.New("id_token nonce mismatch")
+	}
+
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err != nil {
+		return Identity{}, fmt.Errorf("decode id_token claims: %w", err)
+	}
+
+	identity := Identity{
+		Issuer:  idToken.Issuer,
+		Subject: idToken.Subject,
+	}
+	identity.Email, _ = claims["email"].(string)
+	identity.EmailVerified, _ = claims["email_verified"].(bool)
+	identity.Username, _ = claims["preferred_username"].(string)
+	if groups, ok := claims[c.cfg.GroupsClaim].([]any); ok {
+		for _, group := range groups {
+			if name, ok := group.(string); ok {
+				identity.Groups = append(identity.Groups, name)
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+// Role returns the role the identity's groups map to, and false if group
+// mapping is not configured.
+func (c *Client) Role(identity Identity) (string, bool) {
+	if len(c.cfg.AdminGroups) == 0 {
+		return "", false
+	}
+
+	for _, group := range identity.Groups {
+		if slices.Contains(c.cfg.AdminGroups, group) {
+			return "admin", true
+		}
+	}
+	return "user", true
+}
+
+func (c *Client) AutoProvision() bool {
+	return c.cfg.AutoProvision
+}
+
+// NewLoginAttempt returns fresh random values for one sign-in: the OAuth2
+// state, the OIDC nonce and the PKCE code verifier.
+func NewLoginAttempt() (state, nonce, verifier string) {
+	return rand.Text(), rand.Text(), oauth2.GenerateVerifier()
+}
@@ -0,0 +1,67 @@
+// Package tracing configures OpenTelemetry. The exporter is chosen by the
+// tracing.exporter setting: "otlp" sends spans over OTLP/HTTP and honours the
+// standard OTEL_EXPORTER_OTLP_* variables, "stdout" prints them for local
+// runs, and "none" (the default) records nothing. W3C trace context is
+// propagated in every case.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// ServiceName is used unless OTEL_SERVICE_NAME overrides it.
+const ServiceName = "task_manager"
+
+// Setup installs the global tracer provider and propagator. exporterName
+// is none, otlp or stdout. The returned function flushes buffered spans and
+// must be called before exiting.
+func Setup(ctx context.Context, exporterName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch exporterName {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		exporter, err = otlptracehttp.New(ctx)
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporterName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("create trace exporter: %w", err)
+	}
+
+	// resource.WithFromEnv applies OTEL_SERVICE_NAME and
+	// OTEL_RESOURCE_ATTRIBUTES on top of the default name.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(ServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create trace resource: %w", err)
+	}
+
+	// The sampler follows OTEL_TRACES_SAMPLER, defaulting to sampling
+	// everything unless the caller's traceparent says otherwise.
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}